@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time. Code that compares against or schedules
+// from the current time reads it from a Clock, so a simulation can run the
+// same code on virtual time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System is the wall clock.
+var System Clock = systemClock{}
+
+// Virtual is a clock that only moves when it is told to.
+type Virtual struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewVirtual(now time.Time) *Virtual {
+	return &Virtual{now: now}
+}
+
+func (c *Virtual) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t, a clock never goes back so an earlier t is
+// ignored.
+func (c *Virtual) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+func (c *Virtual) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,286 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/mdayat/demi-masa/pkg/clock"
+	"github.com/mdayat/demi-masa/pkg/notifier"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type Kind string
+
+const (
+	PrayerReminderKind     Kind = "PRAYER_REMINDER"
+	PrayerLeadReminderKind Kind = "PRAYER_LEAD_REMINDER"
+	IftarReminderKind      Kind = "IFTAR_REMINDER"
+	LastPrayerReminderKind Kind = "LAST_PRAYER_REMINDER"
+	SahurReminderKind      Kind = "SAHUR_REMINDER"
+	ImsakReminderKind      Kind = "IMSAK_REMINDER"
+	TahajudReminderKind    Kind = "TAHAJUD_REMINDER"
+	DhuhaReminderKind      Kind = "DHUHA_REMINDER"
+	OTPKind                Kind = "OTP"
+
+	PrayerCheckInOnTimeKind      Kind = "PRAYER_CHECK_IN_ON_TIME"
+	PrayerCheckInLateKind        Kind = "PRAYER_CHECK_IN_LATE"
+	PrayerCheckInMissedKind      Kind = "PRAYER_CHECK_IN_MISSED"
+	PrayerCheckInDuplicateKind   Kind = "PRAYER_CHECK_IN_DUPLICATE"
+	PrayerCheckInUnavailableKind Kind = "PRAYER_CHECK_IN_UNAVAILABLE"
+	PrayerCheckInHelpKind        Kind = "PRAYER_CHECK_IN_HELP"
+)
+
+type Locale string
+
+const (
+	IndonesianLocale Locale = "id"
+	EnglishLocale    Locale = "en"
+	JavaneseLocale   Locale = "jv"
+	SundaneseLocale  Locale = "su"
+	DefaultLocale           = IndonesianLocale
+)
+
+var Locales = []Locale{IndonesianLocale, EnglishLocale, JavaneseLocale, SundaneseLocale}
+
+// ParseLocale falls back to the default locale for anything it does not
+// know, so a bad value never stops a message from being sent.
+func ParseLocale(value string) Locale {
+	locale := Locale(value)
+	if slices.Contains(Locales, locale) == false {
+		return DefaultLocale
+	}
+	return locale
+}
+
+// Template is a message with text/template actions over Data.
+type Template struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Data holds the variables a template may use. Time is already formatted in
+// the user's time zone.
+type Data struct {
+	PrayerName string
+	Time       string
+	Minutes    int
+	Streak     int64
+	Code       string
+}
+
+var ErrTemplateNotFound = errors.New("message template not found")
+
+// TemplateRepository holds the templates edited outside of a deploy. It
+// returns ErrTemplateNotFound when a kind has no template for a locale.
+type TemplateRepository interface {
+	GetTemplate(ctx context.Context, kind Kind, locale Locale) (Template, error)
+}
+
+//go:embed templates/*.json
+var defaultTemplateFiles embed.FS
+
+func loadDefaultTemplates() (map[Locale]map[Kind]Template, error) {
+	defaultTemplates := make(map[Locale]map[Kind]Template, len(Locales))
+	for _, locale := range Locales {
+		file, err := defaultTemplateFiles.ReadFile(fmt.Sprintf("templates/%s.json", locale))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read default templates")
+		}
+
+		var templates map[Kind]Template
+		err = json.Unmarshal(file, &templates)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal default templates")
+		}
+		defaultTemplates[locale] = templates
+	}
+
+	if len(defaultTemplates[DefaultLocale]) == 0 {
+		return nil, errors.New(fmt.Sprintf("no default templates for %s", DefaultLocale))
+	}
+	return defaultTemplates, nil
+}
+
+type cacheKey struct {
+	kind   Kind
+	locale Locale
+}
+
+type cachedTemplate struct {
+	template  Template
+	found     bool
+	expiresAt time.Time
+}
+
+// Store renders messages from the repository templates, falling back to the
+// embedded ones. Repository lookups are cached for a while, so an edited
+// template takes effect within the cache TTL.
+type Store struct {
+	repository       TemplateRepository
+	defaultTemplates map[Locale]map[Kind]Template
+	cacheTTL         time.Duration
+	clock            clock.Clock
+	mu               sync.Mutex
+	cache            map[cacheKey]cachedTemplate
+}
+
+func NewStore(repository TemplateRepository, cacheTTL time.Duration, clock clock.Clock) (*Store, error) {
+	defaultTemplates, err := loadDefaultTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		repository:       repository,
+		defaultTemplates: defaultTemplates,
+		cacheTTL:         cacheTTL,
+		clock:            clock,
+		cache:            make(map[cacheKey]cachedTemplate),
+	}, nil
+}
+
+func (s *Store) getRepositoryTemplate(ctx context.Context, kind Kind, locale Locale) (Template, bool, error) {
+	key := cacheKey{kind: kind, locale: locale}
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+
+	if ok && s.clock.Now().Before(cached.expiresAt) {
+		return cached.template, cached.found, nil
+	}
+
+	messageTemplate, err := s.repository.GetTemplate(ctx, kind, locale)
+	if err != nil && errors.Is(err, ErrTemplateNotFound) == false {
+		return Template{}, false, errors.Wrap(err, "failed to get message template")
+	}
+
+	found := err == nil
+	if found {
+		err = ValidateTemplate(messageTemplate)
+		if err != nil {
+			log.Ctx(ctx).
+				Error().
+				Err(err).
+				Str("kind", string(kind)).
+				Str("locale", string(locale)).
+				Bool("alert", true).
+				Msg("invalid message template, embedded template used")
+
+			found = false
+		}
+	}
+
+	s.mu.Lock()
+	s.cache[key] = cachedTemplate{template: messageTemplate, found: found, expiresAt: s.clock.Now().Add(s.cacheTTL)}
+	s.mu.Unlock()
+
+	return messageTemplate, found, nil
+}
+
+// GetTemplate looks up the template of a kind for a locale in the
+// repository, then in the embedded templates, and finally in the default
+// locale of both.
+func (s *Store) GetTemplate(ctx context.Context, kind Kind, locale Locale) (Template, error) {
+	locales := []Locale{locale}
+	if locale != DefaultLocale {
+		locales = append(locales, DefaultLocale)
+	}
+
+	for _, locale := range locales {
+		messageTemplate, found, err := s.getRepositoryTemplate(ctx, kind, locale)
+		if err != nil {
+			return Template{}, err
+		}
+
+		if found {
+			return messageTemplate, nil
+		}
+
+		messageTemplate, found = s.defaultTemplates[locale][kind]
+		if found {
+			return messageTemplate, nil
+		}
+	}
+
+	return Template{}, errors.Wrap(ErrTemplateNotFound, fmt.Sprintf("%s (%s)", kind, locale))
+}
+
+func render(text string, data Data) (string, error) {
+	tmpl, err := template.New("message").Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse message template")
+	}
+
+	var buffer bytes.Buffer
+	err = tmpl.Execute(&buffer, data)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to execute message template")
+	}
+	return buffer.String(), nil
+}
+
+func renderTemplate(messageTemplate Template, data Data) (notifier.Message, error) {
+	subject, err := render(messageTemplate.Subject, data)
+	if err != nil {
+		return notifier.Message{}, err
+	}
+
+	body, err := render(messageTemplate.Body, data)
+	if err != nil {
+		return notifier.Message{}, err
+	}
+
+	return notifier.Message{Subject: subject, Body: body}, nil
+}
+
+// ValidateTemplate renders the template on empty data, so a template that
+// does not parse or uses a variable Data lacks fails before any send does.
+func ValidateTemplate(messageTemplate Template) error {
+	_, err := renderTemplate(messageTemplate, Data{})
+	return err
+}
+
+func (s *Store) getDefaultTemplate(kind Kind, locale Locale) (Template, bool) {
+	messageTemplate, ok := s.defaultTemplates[locale][kind]
+	if !ok {
+		messageTemplate, ok = s.defaultTemplates[DefaultLocale][kind]
+	}
+	return messageTemplate, ok
+}
+
+// Render renders the template of the kind, falling back to the embedded one
+// when a repository template fails on the data.
+func (s *Store) Render(ctx context.Context, kind Kind, locale Locale, data Data) (notifier.Message, error) {
+	messageTemplate, err := s.GetTemplate(ctx, kind, locale)
+	if err != nil {
+		return notifier.Message{}, err
+	}
+
+	msg, err := renderTemplate(messageTemplate, data)
+	if err == nil {
+		return msg, nil
+	}
+
+	defaultTemplate, ok := s.getDefaultTemplate(kind, locale)
+	if !ok || defaultTemplate == messageTemplate {
+		return notifier.Message{}, err
+	}
+
+	log.Ctx(ctx).
+		Error().
+		Err(err).
+		Str("kind", string(kind)).
+		Str("locale", string(locale)).
+		Bool("alert", true).
+		Msg("failed to render message template, embedded template used")
+
+	return renderTemplate(defaultTemplate, data)
+}
@@ -0,0 +1,124 @@
+package message
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdayat/demi-masa/pkg/clock"
+)
+
+type templateRepository map[cacheKey]Template
+
+func (r templateRepository) GetTemplate(ctx context.Context, kind Kind, locale Locale) (Template, error) {
+	messageTemplate, ok := r[cacheKey{kind: kind, locale: locale}]
+	if !ok {
+		return Template{}, ErrTemplateNotFound
+	}
+	return messageTemplate, nil
+}
+
+func TestDefaultTemplatesAreValid(t *testing.T) {
+	defaultTemplates, err := loadDefaultTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for locale, templates := range defaultTemplates {
+		for kind, messageTemplate := range templates {
+			err := ValidateTemplate(messageTemplate)
+			if err != nil {
+				t.Errorf("%s (%s): %v", kind, locale, err)
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := Data{PrayerName: "Zuhur", Time: "12:00", Minutes: 10}
+	tests := []struct {
+		name     string
+		template Template
+		want     string
+	}{
+		{
+			name:     "valid repository template",
+			template: Template{Subject: "{{.PrayerName}}", Body: "{{.PrayerName}} {{.Time}}"},
+			want:     "Zuhur 12:00",
+		},
+		{
+			name:     "template that does not parse",
+			template: Template{Subject: "{{.PrayerName}}", Body: "{{.PrayerName"},
+		},
+		{
+			name:     "template with a variable data lacks",
+			template: Template{Subject: "{{.PrayerName}}", Body: "{{.Location}}"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := templateRepository{{kind: PrayerLeadReminderKind, locale: EnglishLocale}: test.template}
+			store, err := NewStore(repository, time.Minute, clock.System)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msg, err := store.Render(context.Background(), PrayerLeadReminderKind, EnglishLocale, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := test.want
+			if want == "" {
+				defaultTemplate, _ := store.getDefaultTemplate(PrayerLeadReminderKind, EnglishLocale)
+				defaultMsg, err := renderTemplate(defaultTemplate, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want = defaultMsg.Body
+			}
+
+			if msg.Body != want {
+				t.Errorf("body = %q, want %q", msg.Body, want)
+			}
+		})
+	}
+}
+
+func TestTemplateCacheExpiry(t *testing.T) {
+	key := cacheKey{kind: PrayerReminderKind, locale: EnglishLocale}
+	repository := templateRepository{key: Template{Subject: "{{.PrayerName}}", Body: "first"}}
+	virtualClock := clock.NewVirtual(time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC))
+	store, err := NewStore(repository, time.Minute, virtualClock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	_, err = store.GetTemplate(ctx, PrayerReminderKind, EnglishLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository[key] = Template{Subject: "{{.PrayerName}}", Body: "second"}
+	tests := []struct {
+		advance time.Duration
+		want    string
+	}{
+		{advance: 30 * time.Second, want: "first"},
+		{advance: 31 * time.Second, want: "second"},
+	}
+
+	for _, test := range tests {
+		virtualClock.Advance(test.advance)
+		messageTemplate, err := store.GetTemplate(ctx, PrayerReminderKind, EnglishLocale)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if messageTemplate.Body != test.want {
+			t.Errorf("body at %s = %q, want %q", virtualClock.Now().Format(time.TimeOnly), messageTemplate.Body, test.want)
+		}
+	}
+}
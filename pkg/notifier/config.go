@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/twilio/twilio-go"
+)
+
+const LogChannelName = "log"
+
+type Config struct {
+	// ChannelNames is a comma separated list of the enabled channels. The
+	// "log" name serves every channel with the log notifier.
+	ChannelNames         string
+	TwilioClient         *twilio.RestClient
+	WhatsAppSender       string
+	SMSSender            string
+	TwilioStatusCallback string
+	SMTPHost             string
+	SMTPPort             string
+	SMTPUsername         string
+	SMTPPassword         string
+	SMTPSender           string
+	PushClient           PushClient
+	DeviceStore          DeviceStore
+}
+
+// IsChannelEnabled tells whether the channel names enable the real notifier
+// of the channel, the "log" name does not.
+func IsChannelEnabled(channelNames string, channel Channel) bool {
+	for _, name := range strings.Split(channelNames, ",") {
+		if Channel(strings.ToUpper(strings.TrimSpace(name))) == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func NewDispatcherFromConfig(config Config) (Dispatcher, error) {
+	if config.ChannelNames == "" {
+		config.ChannelNames = strings.ToLower(string(WhatsAppChannel))
+	}
+
+	notifiers := make(map[Channel]Notifier, len(Channels))
+	for _, name := range strings.Split(config.ChannelNames, ",") {
+		name = strings.TrimSpace(name)
+		if name == LogChannelName {
+			for _, channel := range Channels {
+				notifiers[channel] = NewLogNotifier(channel)
+			}
+			continue
+		}
+
+		switch Channel(strings.ToUpper(name)) {
+		case WhatsAppChannel:
+			if config.WhatsAppSender == "" {
+				return Dispatcher{}, errors.New("whatsapp sender is not set")
+			}
+			notifiers[WhatsAppChannel] = NewWhatsAppNotifier(config.TwilioClient, config.WhatsAppSender, config.TwilioStatusCallback)
+		case SMSChannel:
+			if config.SMSSender == "" {
+				return Dispatcher{}, errors.New("sms sender is not set")
+			}
+			notifiers[SMSChannel] = NewSMSNotifier(config.TwilioClient, config.SMSSender, config.TwilioStatusCallback)
+		case EmailChannel:
+			if config.SMTPHost == "" || config.SMTPSender == "" {
+				return Dispatcher{}, errors.New("smtp host or sender is not set")
+			}
+			notifiers[EmailChannel] = NewEmailNotifier(
+				config.SMTPHost,
+				config.SMTPPort,
+				config.SMTPUsername,
+				config.SMTPPassword,
+				config.SMTPSender,
+			)
+		case PushChannel:
+			if config.PushClient == nil || config.DeviceStore == nil {
+				return Dispatcher{}, errors.New("push client or device store is not set")
+			}
+			notifiers[PushChannel] = NewPushNotifier(config.PushClient, config.DeviceStore)
+		default:
+			return Dispatcher{}, errors.New(fmt.Sprintf("unknown notification channel: %s", name))
+		}
+	}
+
+	return NewDispatcher(notifiers), nil
+}
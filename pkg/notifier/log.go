@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// logNotifier only logs the message, it stands in for the real channels
+// during development.
+type logNotifier struct {
+	channel Channel
+}
+
+func NewLogNotifier(channel Channel) Notifier {
+	return logNotifier{channel: channel}
+}
+
+func (n logNotifier) Notify(ctx context.Context, recipient Recipient, message Message) (Delivery, error) {
+	log.Ctx(ctx).Info().
+		Str("channel", string(n.channel)).
+		Str("phone_number", recipient.PhoneNumber).
+		Str("email", recipient.Email).
+		Str("subject", message.Subject).
+		Str("body", message.Body).
+		Msg("notification logged")
+	return Delivery{Status: SentStatus}, nil
+}
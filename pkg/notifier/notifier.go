@@ -0,0 +1,101 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type Channel string
+
+const (
+	WhatsAppChannel Channel = "WHATSAPP"
+	SMSChannel      Channel = "SMS"
+	EmailChannel    Channel = "EMAIL"
+	PushChannel     Channel = "PUSH"
+)
+
+var Channels = []Channel{WhatsAppChannel, SMSChannel, EmailChannel, PushChannel}
+
+type Recipient struct {
+	UserID      string
+	PhoneNumber string
+	Email       string
+}
+
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// Delivery is what a channel reports about a message it accepted.
+// ProviderID is set by the channels that report delivery state later on.
+type Delivery struct {
+	Channel    Channel
+	ProviderID string
+	Status     Status
+}
+
+// Notifier delivers a message over a single channel. Implementations
+// return ErrNoAddress when the recipient cannot be reached on it.
+type Notifier interface {
+	Notify(ctx context.Context, recipient Recipient, message Message) (Delivery, error)
+}
+
+var (
+	ErrNoAddress    = errors.New("recipient has no address for the channel")
+	ErrNotDelivered = errors.New("message was not delivered on any channel")
+	ErrUnreachable  = errors.New("recipient has no address on any enabled channel")
+)
+
+func ParseChannels(channelsJSON []byte) ([]Channel, error) {
+	var channels []Channel
+	err := json.Unmarshal(channelsJSON, &channels)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal notification channels")
+	}
+	return channels, nil
+}
+
+// Dispatcher tries the channels in the given order and falls back to the
+// next one when a channel is disabled or fails to deliver. It returns
+// ErrUnreachable when no channel could even try, which no retry fixes, and
+// ErrNotDelivered otherwise.
+type Dispatcher struct {
+	notifiers map[Channel]Notifier
+}
+
+func NewDispatcher(notifiers map[Channel]Notifier) Dispatcher {
+	return Dispatcher{notifiers: notifiers}
+}
+
+func (d Dispatcher) Send(ctx context.Context, channels []Channel, recipient Recipient, message Message) (Delivery, error) {
+	failures := make([]string, 0, len(channels))
+	isReachable := false
+	for _, channel := range channels {
+		notifier, ok := d.notifiers[channel]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s: disabled", channel))
+			continue
+		}
+
+		delivery, err := notifier.Notify(ctx, recipient, message)
+		if err != nil {
+			isReachable = isReachable || errors.Is(err, ErrNoAddress) == false
+			failures = append(failures, fmt.Sprintf("%s: %s", channel, err))
+			continue
+		}
+
+		delivery.Channel = channel
+		return delivery, nil
+	}
+
+	if isReachable == false {
+		return Delivery{}, errors.Wrap(ErrUnreachable, strings.Join(failures, "; "))
+	}
+
+	return Delivery{}, errors.Wrap(ErrNotDelivered, strings.Join(failures, "; "))
+}
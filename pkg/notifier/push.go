@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// PushClient sends a push message to device tokens. It returns the tokens
+// the push service reported as no longer registered.
+type PushClient interface {
+	Send(ctx context.Context, tokens []string, message Message) (invalidTokens []string, err error)
+}
+
+type DeviceStore interface {
+	GetDeviceTokens(ctx context.Context, userID string) ([]string, error)
+	DeleteDeviceTokens(ctx context.Context, tokens []string) error
+}
+
+type pushNotifier struct {
+	client PushClient
+	store  DeviceStore
+}
+
+func NewPushNotifier(client PushClient, store DeviceStore) Notifier {
+	return pushNotifier{client: client, store: store}
+}
+
+func (n pushNotifier) Notify(ctx context.Context, recipient Recipient, message Message) (Delivery, error) {
+	if recipient.UserID == "" {
+		return Delivery{}, ErrNoAddress
+	}
+
+	tokens, err := n.store.GetDeviceTokens(ctx, recipient.UserID)
+	if err != nil {
+		return Delivery{}, errors.Wrap(err, "failed to get device tokens")
+	}
+
+	if len(tokens) == 0 {
+		return Delivery{}, ErrNoAddress
+	}
+
+	invalidTokens, sendErr := n.client.Send(ctx, tokens, message)
+	if len(invalidTokens) != 0 {
+		err = n.store.DeleteDeviceTokens(ctx, invalidTokens)
+		if err != nil {
+			return Delivery{}, errors.Wrap(err, "failed to delete invalid device tokens")
+		}
+	}
+
+	if sendErr != nil {
+		return Delivery{}, errors.Wrap(sendErr, "failed to send push message")
+	}
+	return Delivery{Status: SentStatus}, nil
+}
@@ -0,0 +1,129 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// An email that takes longer than this to send, when the caller set no
+// deadline, is given up on rather than holding a sender forever.
+const smtpTimeout = 30 * time.Second
+
+type smtpNotifier struct {
+	host   string
+	addr   string
+	auth   smtp.Auth
+	sender string
+}
+
+func NewEmailNotifier(host, port, username, password, sender string) Notifier {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return smtpNotifier{host: host, addr: net.JoinHostPort(host, port), auth: auth, sender: sender}
+}
+
+func (n smtpNotifier) Notify(ctx context.Context, recipient Recipient, message Message) (Delivery, error) {
+	if recipient.Email == "" {
+		return Delivery{}, ErrNoAddress
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", n.sender)
+	fmt.Fprintf(&builder, "To: %s\r\n", recipient.Email)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	err := n.sendMail(ctx, recipient.Email, []byte(builder.String()))
+	if err != nil {
+		return Delivery{}, errors.Wrap(err, "failed to send email")
+	}
+	return Delivery{Status: SentStatus}, nil
+}
+
+// sendMail does what smtp.SendMail does on a connection bound to ctx, a
+// server that stops answering fails the send once ctx is done.
+func (n smtpNotifier) sendMail(ctx context.Context, to string, msg []byte) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to dial smtp server")
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return errors.Wrap(err, "failed to set smtp connection deadline")
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		return errors.Wrap(err, "failed to create smtp client")
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: n.host})
+		if err != nil {
+			return errors.Wrap(err, "failed to start tls")
+		}
+	}
+
+	if n.auth != nil {
+		err = client.Auth(n.auth)
+		if err != nil {
+			return errors.Wrap(err, "failed to authenticate")
+		}
+	}
+
+	err = client.Mail(n.sender)
+	if err != nil {
+		return errors.Wrap(err, "failed to set sender")
+	}
+
+	err = client.Rcpt(to)
+	if err != nil {
+		return errors.Wrap(err, "failed to set recipient")
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "failed to start data")
+	}
+
+	_, err = writer.Write(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to write message")
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to close data")
+	}
+
+	return client.Quit()
+}
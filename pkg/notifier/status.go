@@ -0,0 +1,54 @@
+package notifier
+
+import "slices"
+
+// Status is the delivery state of an outbound message. Messages on channels
+// without delivery callbacks stay SENT.
+type Status string
+
+const (
+	QueuedStatus      Status = "QUEUED"
+	SentStatus        Status = "SENT"
+	DeliveredStatus   Status = "DELIVERED"
+	ReadStatus        Status = "READ"
+	UndeliveredStatus Status = "UNDELIVERED"
+	FailedStatus      Status = "FAILED"
+)
+
+// ParseTwilioStatus maps a twilio message status onto Status. It returns
+// false for the inbound statuses.
+func ParseTwilioStatus(value string) (Status, bool) {
+	switch value {
+	case "accepted", "scheduled", "queued", "sending":
+		return QueuedStatus, true
+	case "sent":
+		return SentStatus, true
+	case "delivered":
+		return DeliveredStatus, true
+	case "read":
+		return ReadStatus, true
+	case "undelivered":
+		return UndeliveredStatus, true
+	case "failed", "canceled":
+		return FailedStatus, true
+	default:
+		return "", false
+	}
+}
+
+var statusProgression = []Status{QueuedStatus, SentStatus, DeliveredStatus, ReadStatus}
+
+// PrecedingStatuses returns the statuses a message may move to s from.
+// Status callbacks can arrive out of order, so a message never moves back
+// and a failure is final.
+func (s Status) PrecedingStatuses() []Status {
+	if s == UndeliveredStatus || s == FailedStatus {
+		return []Status{QueuedStatus, SentStatus}
+	}
+
+	i := slices.Index(statusProgression, s)
+	if i == -1 {
+		return nil
+	}
+	return statusProgression[:i]
+}
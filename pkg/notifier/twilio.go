@@ -0,0 +1,70 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/twilio/twilio-go"
+	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+type messageCreator interface {
+	CreateMessage(params *twilioApi.CreateMessageParams) (*twilioApi.ApiV2010Message, error)
+}
+
+type twilioNotifier struct {
+	api            messageCreator
+	sender         string
+	prefix         string
+	statusCallback string
+}
+
+const whatsAppPrefix = "whatsapp:"
+
+// The status callback, when set, is the URL twilio reports the delivery
+// state of every message to.
+func NewWhatsAppNotifier(client *twilio.RestClient, sender, statusCallback string) Notifier {
+	return twilioNotifier{
+		api:            client.Api,
+		sender:         strings.TrimPrefix(sender, whatsAppPrefix),
+		prefix:         whatsAppPrefix,
+		statusCallback: statusCallback,
+	}
+}
+
+func NewSMSNotifier(client *twilio.RestClient, sender, statusCallback string) Notifier {
+	return twilioNotifier{api: client.Api, sender: sender, statusCallback: statusCallback}
+}
+
+func (n twilioNotifier) Notify(ctx context.Context, recipient Recipient, message Message) (Delivery, error) {
+	if recipient.PhoneNumber == "" {
+		return Delivery{}, ErrNoAddress
+	}
+
+	params := twilioApi.CreateMessageParams{}
+	params.SetFrom(n.prefix + n.sender)
+	params.SetTo(n.prefix + recipient.PhoneNumber)
+	params.SetBody(message.Body)
+	if n.statusCallback != "" {
+		params.SetStatusCallback(n.statusCallback)
+	}
+
+	resp, err := n.api.CreateMessage(&params)
+	if err != nil {
+		return Delivery{}, errors.Wrap(err, "failed to create twilio message")
+	}
+
+	delivery := Delivery{Status: QueuedStatus}
+	if resp.Sid != nil {
+		delivery.ProviderID = *resp.Sid
+	}
+
+	if resp.Status != nil {
+		status, ok := ParseTwilioStatus(*resp.Status)
+		if ok {
+			delivery.Status = status
+		}
+	}
+	return delivery, nil
+}
@@ -0,0 +1,110 @@
+package prayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type AsrMethod string
+
+const (
+	ShafiiAsrMethod AsrMethod = "SHAFII"
+	HanafiAsrMethod AsrMethod = "HANAFI"
+)
+
+const MaxPrayerOffset = 30
+
+// Adjustment holds the per-user settings applied on top of the shared
+// calendar of a location. The zero value leaves the calendar untouched.
+type Adjustment struct {
+	AsrMethod AsrMethod
+	// Offsets are extra minutes keyed by fardhu prayer name.
+	Offsets map[string]int
+}
+
+func NewAdjustment(asrMethod string, offsets []byte) (Adjustment, error) {
+	adjustment := Adjustment{AsrMethod: AsrMethod(asrMethod)}
+	if len(offsets) == 0 {
+		return adjustment, nil
+	}
+
+	err := json.Unmarshal(offsets, &adjustment.Offsets)
+	if err != nil {
+		return Adjustment{}, errors.Wrap(err, "failed to unmarshal prayer offsets")
+	}
+
+	return adjustment, nil
+}
+
+func ValidateOffsets(offsets map[string]int) error {
+	for name, minutes := range offsets {
+		if IsFardhu(name) == false {
+			return errors.New(fmt.Sprintf("unknown prayer name: %s", name))
+		}
+
+		if minutes < -MaxPrayerOffset || minutes > MaxPrayerOffset {
+			return errors.New(fmt.Sprintf("offset of %s is out of range: %d", name, minutes))
+		}
+	}
+	return nil
+}
+
+// hanafiAsrDelay returns how much later the hanafi asr (shadow twice the
+// object length) falls than the shafi'i one on the given day.
+func hanafiAsrDelay(location Location, date time.Time) time.Duration {
+	year, month, day := date.Date()
+	calculator := sunCalculator{
+		latitude:  location.Latitude,
+		longitude: location.Longitude,
+		jd:        julianDate(year, int(month), day),
+	}
+
+	shafiiAsr := 15 - location.Longitude/15
+	hanafiAsr := shafiiAsr
+	for i := 0; i < 2; i++ {
+		shafiiAsr = calculator.asrTime(1, shafiiAsr)
+		hanafiAsr = calculator.asrTime(2, hanafiAsr)
+	}
+
+	delay := hanafiAsr - shafiiAsr
+	if math.IsNaN(delay) || delay < 0 {
+		return 0
+	}
+	return time.Duration(delay * float64(time.Hour)).Round(time.Minute)
+}
+
+// Adjust applies the asr method and offsets to the fardhu prayers, the
+// sunnah times are derived again from the adjusted prayers.
+func (p Prayers) Adjust(location Location, adjustment Adjustment) Prayers {
+	if adjustment.AsrMethod != HanafiAsrMethod && len(adjustment.Offsets) == 0 {
+		return p
+	}
+
+	var asrDelay time.Duration
+	if adjustment.AsrMethod == HanafiAsrMethod {
+		// Zuhur in UTC always falls on the local solar date.
+		if zuhur, ok := p.Get(ZuhurPrayerName); ok {
+			asrDelay = hanafiAsrDelay(location, time.Unix(zuhur.UnixTime, 0).UTC())
+		}
+	}
+
+	adjusted := make(Prayers, 0, len(p))
+	for _, prayer := range p {
+		switch {
+		case IsFardhu(prayer.Name):
+			if prayer.Name == AsarPrayerName {
+				prayer.UnixTime += int64(asrDelay.Seconds())
+			}
+			prayer.UnixTime += int64(adjustment.Offsets[prayer.Name]) * 60
+		case prayer.Name != SunriseTimeName:
+			continue
+		}
+		adjusted = append(adjusted, prayer)
+	}
+
+	return WithSunnahTimes(adjusted)
+}
@@ -0,0 +1,260 @@
+package prayer
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type CalculationParams struct {
+	FajrAngle       float64
+	IshaAngle       float64
+	AsrShadowFactor float64
+	Ihtiyat         time.Duration
+}
+
+// Kemenag RI criteria: fajr at -20°, isha at -18°, shafi'i asr and
+// 2 minutes of ihtiyat added to every prayer (subtracted from sunrise).
+var KemenagCalculationParams = CalculationParams{
+	FajrAngle:       20,
+	IshaAngle:       18,
+	AsrShadowFactor: 1,
+	Ihtiyat:         2 * time.Minute,
+}
+
+const sunriseAngle = 0.833
+
+// ErrNoSunriseOrSunset is returned for a place and date of polar day or
+// polar night. There is no sunrise or magrib to work the other times out
+// from and no rule that all the calculation methods agree on.
+var ErrNoSunriseOrSunset = errors.New("sun does not rise or set")
+
+func degToRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radToDeg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+func fixAngle(angle float64) float64 {
+	angle = math.Mod(angle, 360)
+	if angle < 0 {
+		angle += 360
+	}
+	return angle
+}
+
+func fixHour(hour float64) float64 {
+	hour = math.Mod(hour, 24)
+	if hour < 0 {
+		hour += 24
+	}
+	return hour
+}
+
+func julianDate(year, month, day int) float64 {
+	if month <= 2 {
+		year--
+		month += 12
+	}
+
+	a := math.Floor(float64(year) / 100)
+	b := 2 - a + math.Floor(a/4)
+	return math.Floor(365.25*float64(year+4716)) + math.Floor(30.6001*float64(month+1)) + float64(day) + b - 1524.5
+}
+
+// sunPosition returns the declination (degrees) and equation of time (hours)
+// of the sun at the given julian date.
+func sunPosition(jd float64) (declination float64, equationOfTime float64) {
+	d := jd - 2451545.0
+	g := fixAngle(357.529 + 0.98560028*d)
+	q := fixAngle(280.459 + 0.98564736*d)
+	l := fixAngle(q + 1.915*math.Sin(degToRad(g)) + 0.020*math.Sin(degToRad(2*g)))
+	e := 23.439 - 0.00000036*d
+
+	rightAscension := radToDeg(math.Atan2(math.Cos(degToRad(e))*math.Sin(degToRad(l)), math.Cos(degToRad(l)))) / 15
+	declination = radToDeg(math.Asin(math.Sin(degToRad(e)) * math.Sin(degToRad(l))))
+	equationOfTime = q/15 - fixHour(rightAscension)
+	if equationOfTime > 12 {
+		equationOfTime -= 24
+	} else if equationOfTime < -12 {
+		equationOfTime += 24
+	}
+
+	return declination, equationOfTime
+}
+
+type sunCalculator struct {
+	latitude  float64
+	longitude float64
+	jd        float64
+}
+
+// midDay returns the solar transit in UTC hours.
+func (s sunCalculator) midDay(approxHour float64) float64 {
+	_, eqt := sunPosition(s.jd + approxHour/24)
+	return 12 - eqt - s.longitude/15
+}
+
+// angleTime returns the UTC hour when the sun is at the given angle below
+// the horizon, before transit when ccw is true and after it otherwise.
+// It returns NaN when the sun never reaches that angle.
+func (s sunCalculator) angleTime(angle, approxHour float64, ccw bool) float64 {
+	decl, _ := sunPosition(s.jd + approxHour/24)
+	noon := s.midDay(approxHour)
+	cosHourAngle := (-math.Sin(degToRad(angle)) - math.Sin(degToRad(decl))*math.Sin(degToRad(s.latitude))) /
+		(math.Cos(degToRad(decl)) * math.Cos(degToRad(s.latitude)))
+
+	hourAngle := radToDeg(math.Acos(cosHourAngle)) / 15
+	if ccw {
+		return noon - hourAngle
+	}
+	return noon + hourAngle
+}
+
+func (s sunCalculator) asrTime(shadowFactor, approxHour float64) float64 {
+	decl, _ := sunPosition(s.jd + approxHour/24)
+	angle := -radToDeg(math.Atan(1 / (shadowFactor + math.Tan(degToRad(math.Abs(s.latitude-decl))))))
+	return s.angleTime(angle, approxHour, false)
+}
+
+type dayTimes struct {
+	fajr    float64
+	sunrise float64
+	dhuhr   float64
+	asr     float64
+	maghrib float64
+	isha    float64
+}
+
+func (s sunCalculator) compute(params CalculationParams) (dayTimes, error) {
+	offset := s.longitude / 15
+	times := dayTimes{
+		fajr:    5 - offset,
+		sunrise: 6 - offset,
+		dhuhr:   12 - offset,
+		asr:     15 - offset,
+		maghrib: 18 - offset,
+		isha:    19 - offset,
+	}
+
+	// Each event is evaluated at the sun position of its previous estimate,
+	// two passes are enough for minute precision.
+	for i := 0; i < 2; i++ {
+		times = dayTimes{
+			fajr:    s.angleTime(params.FajrAngle, times.fajr, true),
+			sunrise: s.angleTime(sunriseAngle, times.sunrise, true),
+			dhuhr:   s.midDay(times.dhuhr),
+			asr:     s.asrTime(params.AsrShadowFactor, times.asr),
+			maghrib: s.angleTime(sunriseAngle, times.maghrib, false),
+			isha:    s.angleTime(params.IshaAngle, times.isha, false),
+		}
+	}
+
+	if math.IsNaN(times.sunrise) || math.IsNaN(times.maghrib) || math.IsNaN(times.asr) {
+		return dayTimes{}, ErrNoSunriseOrSunset
+	}
+
+	// At high latitudes the sun may never reach the fajr or isha angle,
+	// fall back to the angle based portion of the night.
+	night := 24 - (times.maghrib - times.sunrise)
+	if math.IsNaN(times.fajr) || times.sunrise-times.fajr > night*params.FajrAngle/60 {
+		times.fajr = times.sunrise - night*params.FajrAngle/60
+	}
+
+	if math.IsNaN(times.isha) || times.isha-times.maghrib > night*params.IshaAngle/60 {
+		times.isha = times.maghrib + night*params.IshaAngle/60
+	}
+
+	return times, nil
+}
+
+func hourToTime(midnight time.Time, hour float64, roundUp bool) time.Time {
+	t := midnight.Add(time.Duration(hour * float64(time.Hour)))
+	if roundUp && t.Truncate(time.Minute) != t {
+		return t.Truncate(time.Minute).Add(time.Minute)
+	}
+	return t.Truncate(time.Minute)
+}
+
+func CalculatePrayers(latitude, longitude float64, date time.Time, params CalculationParams) (Prayers, error) {
+	year, month, day := date.Date()
+	calculator := sunCalculator{
+		latitude:  latitude,
+		longitude: longitude,
+		jd:        julianDate(year, int(month), day),
+	}
+
+	times, err := calculator.compute(params)
+	if err != nil {
+		return nil, errors.Wrap(err, date.Format(time.DateOnly))
+	}
+
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	return Prayers{
+		{
+			Name:     SubuhPrayerName,
+			UnixTime: hourToTime(midnight, times.fajr, true).Add(params.Ihtiyat).Unix(),
+		},
+		{
+			Name:     SunriseTimeName,
+			UnixTime: hourToTime(midnight, times.sunrise, false).Add(-params.Ihtiyat).Unix(),
+		},
+		{
+			Name:     ZuhurPrayerName,
+			UnixTime: hourToTime(midnight, times.dhuhr, true).Add(params.Ihtiyat).Unix(),
+		},
+		{
+			Name:     AsarPrayerName,
+			UnixTime: hourToTime(midnight, times.asr, true).Add(params.Ihtiyat).Unix(),
+		},
+		{
+			Name:     MagribPrayerName,
+			UnixTime: hourToTime(midnight, times.maghrib, true).Add(params.Ihtiyat).Unix(),
+		},
+		{
+			Name:     IsyaPrayerName,
+			UnixTime: hourToTime(midnight, times.isha, true).Add(params.Ihtiyat).Unix(),
+		},
+	}, nil
+}
+
+// CheckSunriseAndSunset tells whether the sun rises and sets on every day of
+// the year at the coordinates. The days nearest to polar day or night are
+// the solstices, a place that has a sunrise and a magrib on both has them
+// all year round.
+func CheckSunriseAndSunset(latitude, longitude float64, year int) error {
+	for _, month := range []time.Month{time.June, time.December} {
+		_, err := CalculatePrayers(latitude, longitude, time.Date(year, month, 21, 0, 0, 0, 0, time.UTC), KemenagCalculationParams)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CalculatePrayerCalendar(
+	latitude float64,
+	longitude float64,
+	year int,
+	month int,
+	location *time.Location,
+	params CalculationParams,
+) (PrayerCalendar, error) {
+	firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, location)
+	numOfDays := firstDay.AddDate(0, 1, -1).Day()
+
+	prayerCalendar := make(PrayerCalendar, numOfDays)
+	for i := 0; i < numOfDays; i++ {
+		prayers, err := CalculatePrayers(latitude, longitude, firstDay.AddDate(0, 0, i), params)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to calculate prayers")
+		}
+		prayerCalendar[i] = prayers
+	}
+
+	return prayerCalendar, nil
+}
@@ -0,0 +1,159 @@
+package prayer
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/pkg/errors"
+)
+
+// assertPrayerTimes checks the prayers against the wall clock times of the
+// time zone, within a tolerance for the rounding of the ihtiyat.
+func assertPrayerTimes(t *testing.T, prayers Prayers, timeZone *time.Location, date time.Time, want map[string]string) {
+	t.Helper()
+	const tolerance = 2 * time.Minute
+	for name, clock := range want {
+		prayer, ok := prayers.Get(name)
+		if !ok {
+			t.Errorf("missing %s", name)
+			continue
+		}
+
+		wantTime, err := time.ParseInLocation(time.DateTime, date.Format(time.DateOnly)+" "+clock+":00", timeZone)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gotTime := time.Unix(prayer.UnixTime, 0).In(timeZone)
+		diff := gotTime.Sub(wantTime)
+		if diff < -tolerance || diff > tolerance {
+			t.Errorf("%s = %s, want %s", name, gotTime.Format("15:04"), clock)
+		}
+	}
+}
+
+func TestCalculatePrayers(t *testing.T) {
+	// Reference times worked out with the NOAA solar equations on the
+	// Kemenag RI criteria, -20° fajr, -18° isha, shafi'i asr and 2 minutes
+	// of ihtiyat.
+	tests := []struct {
+		city      string
+		latitude  float64
+		longitude float64
+		timeZone  string
+		date      string
+		want      map[string]string
+	}{
+		{
+			city: "Jakarta", latitude: -6.2088, longitude: 106.8456, timeZone: "Asia/Jakarta", date: "2026-01-15",
+			want: map[string]string{SubuhPrayerName: "04:27", SunriseTimeName: "05:47", ZuhurPrayerName: "12:04", AsarPrayerName: "15:29", MagribPrayerName: "18:17", IsyaPrayerName: "19:32"},
+		},
+		{
+			city: "Surabaya", latitude: -7.2575, longitude: 112.7521, timeZone: "Asia/Jakarta", date: "2026-04-15",
+			want: map[string]string{SubuhPrayerName: "04:15", SunriseTimeName: "05:29", ZuhurPrayerName: "11:31", AsarPrayerName: "14:51", MagribPrayerName: "17:29", IsyaPrayerName: "18:39"},
+		},
+		{
+			city: "Makassar", latitude: -5.1477, longitude: 119.4327, timeZone: "Asia/Makassar", date: "2026-07-15",
+			want: map[string]string{SubuhPrayerName: "04:52", SunriseTimeName: "06:11", ZuhurPrayerName: "12:10", AsarPrayerName: "15:33", MagribPrayerName: "18:06", IsyaPrayerName: "19:20"},
+		},
+		{
+			city: "Jayapura", latitude: -2.5337, longitude: 140.7181, timeZone: "Asia/Jayapura", date: "2026-10-15",
+			want: map[string]string{SubuhPrayerName: "04:02", SunriseTimeName: "05:16", ZuhurPrayerName: "11:25", AsarPrayerName: "14:36", MagribPrayerName: "17:30", IsyaPrayerName: "18:39"},
+		},
+		{
+			city: "Banda Aceh", latitude: 5.5483, longitude: 95.3238, timeZone: "Asia/Jakarta", date: "2026-10-15",
+			want: map[string]string{SubuhPrayerName: "05:09", SunriseTimeName: "06:22", ZuhurPrayerName: "12:27", AsarPrayerName: "15:45", MagribPrayerName: "18:26", IsyaPrayerName: "19:36"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.city, func(t *testing.T) {
+			timeZone, err := time.LoadLocation(test.timeZone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			date, err := time.ParseInLocation(time.DateOnly, test.date, timeZone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prayers, err := CalculatePrayers(test.latitude, test.longitude, date, KemenagCalculationParams)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertPrayerTimes(t, prayers, timeZone, date, test.want)
+		})
+	}
+}
+
+func TestCalculatePrayersAtHighLatitude(t *testing.T) {
+	// The sun stays above -18° all night in Oslo around the june solstice,
+	// subuh and isya fall back to the angle based portion of the night.
+	location := NewLocation("Oslo", 59.9139, 10.7522, "Europe/Oslo")
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Date(2026, time.June, 21, 0, 0, 0, 0, timeZone)
+	prayers, err := CalculatePrayers(location.Latitude, location.Longitude, date, KemenagCalculationParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPrayerTimes(t, prayers, timeZone, date, map[string]string{SunriseTimeName: "03:52", ZuhurPrayerName: "13:21", MagribPrayerName: "22:46"})
+
+	prayerCalendar, err := CalculatePrayerCalendar(location.Latitude, location.Longitude, 2026, int(time.June), timeZone, KemenagCalculationParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ValidatePrayerCalendar(prayerCalendar, location, 2026, int(time.June))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCalculatePrayersInPolarDayAndNight(t *testing.T) {
+	timeZone, err := time.LoadLocation("Europe/Oslo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tromsø has midnight sun in june and polar night in december.
+	for _, month := range []time.Month{time.June, time.December} {
+		date := time.Date(2026, month, 21, 0, 0, 0, 0, timeZone)
+		_, err := CalculatePrayers(69.6492, 18.9553, date, KemenagCalculationParams)
+		if errors.Is(err, ErrNoSunriseOrSunset) == false {
+			t.Errorf("%s: err = %v, want %v", month, err, ErrNoSunriseOrSunset)
+		}
+	}
+}
+
+func TestCheckSunriseAndSunset(t *testing.T) {
+	tests := []struct {
+		city      string
+		latitude  float64
+		longitude float64
+		isValid   bool
+	}{
+		{city: "Jakarta", latitude: -6.2088, longitude: 106.8456, isValid: true},
+		{city: "Oslo", latitude: 59.9139, longitude: 10.7522, isValid: true},
+		{city: "Tromsø", latitude: 69.6492, longitude: 18.9553, isValid: false},
+		{city: "McMurdo", latitude: -77.8419, longitude: 166.6863, isValid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.city, func(t *testing.T) {
+			err := CheckSunriseAndSunset(test.latitude, test.longitude, 2026)
+			if test.isValid && err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+
+			if test.isValid == false && errors.Is(err, ErrNoSunriseOrSunset) == false {
+				t.Errorf("err = %v, want %v", err, ErrNoSunriseOrSunset)
+			}
+		})
+	}
+}
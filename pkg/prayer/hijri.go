@@ -0,0 +1,118 @@
+package prayer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const RamadanMonth = 9
+
+var hijriMonthNames = []string{
+	"Muharram",
+	"Safar",
+	"Rabiul Awal",
+	"Rabiul Akhir",
+	"Jumadil Awal",
+	"Jumadil Akhir",
+	"Rajab",
+	"Syakban",
+	"Ramadan",
+	"Syawal",
+	"Zulkaidah",
+	"Zulhijah",
+}
+
+type HijriDate struct {
+	Year      int    `json:"year"`
+	Month     int    `json:"month"`
+	Day       int    `json:"day"`
+	MonthName string `json:"month_name"`
+}
+
+func (d HijriDate) String() string {
+	return fmt.Sprintf("%d %s %d H", d.Day, d.MonthName, d.Year)
+}
+
+func (d HijriDate) IsRamadan() bool {
+	return d.Month == RamadanMonth
+}
+
+// HijriCalendar converts with the tabular islamic calendar. The offsets, in
+// days and keyed by hijri year, apply the Kemenag isbat decision whenever it
+// differs from the tabular one.
+type HijriCalendar struct {
+	offsets map[int]int
+}
+
+func NewHijriCalendar(offsets map[int]int) HijriCalendar {
+	return HijriCalendar{offsets: offsets}
+}
+
+// ParseHijriOffsets parses offsets written as "1446:-1,1447:1".
+func ParseHijriOffsets(value string) (map[int]int, error) {
+	offsets := make(map[int]int)
+	if value == "" {
+		return offsets, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		yearString, offsetString, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("invalid hijri offset: %s", pair))
+		}
+
+		year, err := strconv.Atoi(yearString)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse hijri offset year")
+		}
+
+		offset, err := strconv.Atoi(offsetString)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse hijri offset days")
+		}
+
+		offsets[year] = offset
+	}
+
+	return offsets, nil
+}
+
+func julianDayNumber(year, month, day int) int {
+	a := (14 - month) / 12
+	y := year + 4800 - a
+	m := month + 12*a - 3
+	return day + (153*m+2)/5 + 365*y + y/4 - y/100 + y/400 - 32045
+}
+
+func tabularHijriDate(jdn int) HijriDate {
+	l := jdn - 1948440 + 10632
+	n := (l - 1) / 10631
+	l = l - 10631*n + 354
+	j := ((10985-l)/5316)*((50*l)/17719) + (l/5670)*((43*l)/15238)
+	l = l - ((30-j)/15)*((17719*j)/50) - (j/16)*((15238*j)/43) + 29
+	month := (24 * l) / 709
+	day := l - (709*month)/24
+	year := 30*n + j - 30
+
+	return HijriDate{
+		Year:      year,
+		Month:     month,
+		Day:       day,
+		MonthName: hijriMonthNames[month-1],
+	}
+}
+
+// FromGregorian converts the civil date of t, as seen in t's location.
+func (c HijriCalendar) FromGregorian(t time.Time) HijriDate {
+	jdn := julianDayNumber(t.Year(), int(t.Month()), t.Day())
+	hijriDate := tabularHijriDate(jdn)
+	if offset, ok := c.offsets[hijriDate.Year]; ok && offset != 0 {
+		hijriDate = tabularHijriDate(jdn + offset)
+	}
+
+	return hijriDate
+}
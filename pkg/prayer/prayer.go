@@ -11,7 +11,6 @@ import (
 
 	"github.com/avast/retry-go/v4"
 	"github.com/pkg/errors"
-	"github.com/redis/go-redis/v9"
 )
 
 type Prayer struct {
@@ -22,13 +21,45 @@ type Prayer struct {
 type Prayers []Prayer
 type PrayerCalendar []Prayers
 
+func (p Prayers) Get(name string) (Prayer, bool) {
+	for _, prayer := range p {
+		if prayer.Name == name {
+			return prayer, true
+		}
+	}
+	return Prayer{}, false
+}
+
+func IsFardhu(name string) bool {
+	switch name {
+	case SubuhPrayerName, ZuhurPrayerName, AsarPrayerName, MagribPrayerName, IsyaPrayerName:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p Prayers) Fardhu() Prayers {
+	fardhu := make(Prayers, 0, 5)
+	for _, prayer := range p {
+		if IsFardhu(prayer.Name) {
+			fardhu = append(fardhu, prayer)
+		}
+	}
+	return fardhu
+}
+
 var (
-	SubuhPrayerName  = "Subuh"
-	ZuhurPrayerName  = "Zuhur"
-	AsarPrayerName   = "Asar"
-	MagribPrayerName = "Magrib"
-	IsyaPrayerName   = "Isya"
-	SunriseTimeName  = "Sunrise"
+	SubuhPrayerName    = "Subuh"
+	ZuhurPrayerName    = "Zuhur"
+	AsarPrayerName     = "Asar"
+	MagribPrayerName   = "Magrib"
+	IsyaPrayerName     = "Isya"
+	SunriseTimeName    = "Sunrise"
+	ImsakTimeName      = "Imsak"
+	DhuhaStartTimeName = "DhuhaStart"
+	DhuhaEndTimeName   = "DhuhaEnd"
+	TahajudTimeName    = "Tahajud"
 )
 
 type aladhanPrayerCalendar struct {
@@ -131,8 +162,8 @@ func ParseAladhanPrayerCalendar(prayerCalendar []aladhanPrayerCalendar, location
 	return parsedPrayerCalendar, nil
 }
 
-func GetAladhanPrayerCalendar(url string) ([]aladhanPrayerCalendar, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+func GetAladhanPrayerCalendar(ctx context.Context, url string) ([]aladhanPrayerCalendar, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
 
 	body, err := retry.DoWithData(
@@ -192,153 +223,3 @@ func GetAladhanPrayerCalendar(url string) ([]aladhanPrayerCalendar, error) {
 
 	return body, nil
 }
-
-func GetNextPrayerByTime(
-	prayerCalendar PrayerCalendar,
-	lastDayPrayers Prayers,
-	currentDay int,
-	currentUnixTime int64,
-) (nextPrayer Prayer) {
-	var todayPrayers Prayers
-	if lastDayPrayers == nil {
-		todayPrayers = prayerCalendar[currentDay-1]
-	} else {
-		todayPrayers = lastDayPrayers
-	}
-
-	for _, prayer := range todayPrayers {
-		if prayer.Name == SunriseTimeName {
-			continue
-		}
-
-		if prayer.UnixTime > currentUnixTime {
-			nextPrayer = prayer
-			break
-		}
-	}
-
-	if nextPrayer.Name != "" {
-		return nextPrayer
-	}
-
-	var tomorrowPrayers Prayers
-	if lastDayPrayers == nil {
-		currentDay++
-		tomorrowPrayers = prayerCalendar[currentDay-1]
-	} else {
-		tomorrowPrayers = prayerCalendar[0]
-	}
-
-	nextPrayer = tomorrowPrayers[0]
-	return nextPrayer
-}
-
-func GetNextPrayerByName(
-	prayerCalendar PrayerCalendar,
-	lastDayPrayers Prayers,
-	currentDay int,
-	currentPrayerName string,
-) (nextPrayer Prayer) {
-	var todayPrayers Prayers
-	if lastDayPrayers == nil {
-		todayPrayers = prayerCalendar[currentDay-1]
-	} else {
-		todayPrayers = lastDayPrayers
-	}
-
-	switch currentPrayerName {
-	case SubuhPrayerName:
-		nextPrayer = todayPrayers[2]
-
-	case ZuhurPrayerName:
-		nextPrayer = todayPrayers[3]
-
-	case AsarPrayerName:
-		nextPrayer = todayPrayers[4]
-
-	case MagribPrayerName:
-		nextPrayer = todayPrayers[5]
-
-	default:
-		var tomorrowPrayers Prayers
-		if lastDayPrayers == nil {
-			currentDay++
-			tomorrowPrayers = prayerCalendar[currentDay-1]
-		} else {
-			tomorrowPrayers = prayerCalendar[0]
-		}
-
-		nextPrayer = tomorrowPrayers[0]
-	}
-
-	return nextPrayer
-}
-
-func MakePrayerCalendarKey(timeZone string) string {
-	return fmt.Sprintf("prayer:calendar:%s", timeZone)
-}
-
-func MakeLastDayPrayerKey(timeZone string) string {
-	return fmt.Sprintf("prayer:last_day:%s", timeZone)
-}
-
-func MakePenultimateDayPrayerKey(timeZone string) string {
-	return fmt.Sprintf("prayer:penultimate_day:%s", timeZone)
-}
-
-func GetPrayerCalendar(ctx context.Context, redisClient *redis.Client, timeZone string) (PrayerCalendar, error) {
-	prayerCalendarJSON, err := redisClient.Get(ctx, MakePrayerCalendarKey(timeZone)).Result()
-	if err != nil {
-		return nil, err
-	}
-
-	var prayerCalendar PrayerCalendar
-	err = json.Unmarshal([]byte(prayerCalendarJSON), &prayerCalendar)
-	if err != nil {
-		return nil, err
-	}
-
-	return prayerCalendar, nil
-}
-
-func GetLastDayPrayer(ctx context.Context, redisClient *redis.Client, timeZone string) (Prayers, error) {
-	lastDayPrayerJSON, err := redisClient.Get(ctx, MakeLastDayPrayerKey(timeZone)).Result()
-	if err != nil {
-		return nil, err
-	}
-
-	var lastDayPrayer Prayers
-	err = json.Unmarshal([]byte(lastDayPrayerJSON), &lastDayPrayer)
-	if err != nil {
-		return nil, err
-	}
-
-	return lastDayPrayer, nil
-}
-
-func GetPenultimateDayPrayer(ctx context.Context, redisClient *redis.Client, timeZone string) (Prayers, error) {
-	penultimateDayPrayerJSON, err := redisClient.Get(ctx, MakeLastDayPrayerKey(timeZone)).Result()
-	if err != nil {
-		return nil, err
-	}
-
-	var penultimateDayPrayer Prayers
-	err = json.Unmarshal([]byte(penultimateDayPrayerJSON), &penultimateDayPrayer)
-	if err != nil {
-		return nil, err
-	}
-
-	return penultimateDayPrayer, nil
-}
-
-func IsLastDay(currentTime time.Time) bool {
-	firstDayNextMonth := time.Date(currentTime.Year(), currentTime.Month()+1, 1, 0, 0, 0, 0, currentTime.Location())
-	lastDayCurrentMonth := firstDayNextMonth.AddDate(0, 0, -1)
-	return currentTime.Day() == lastDayCurrentMonth.Day()
-}
-
-func IsPenultimateDay(currentTime time.Time) bool {
-	firstDayNextMonth := time.Date(currentTime.Year(), currentTime.Month()+1, 1, 0, 0, 0, 0, currentTime.Location())
-	penultimateDayCurrentMonth := firstDayNextMonth.AddDate(0, 0, -2)
-	return currentTime.Day() == penultimateDayCurrentMonth.Day()
-}
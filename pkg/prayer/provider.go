@@ -0,0 +1,199 @@
+package prayer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Location struct {
+	City      string
+	Latitude  float64
+	Longitude float64
+	TimeZone  string
+}
+
+// NewLocation rounds the coordinates to 0.1° (about 11 km, well under a
+// minute of prayer time difference) so nearby users share one calendar.
+func NewLocation(city string, latitude, longitude float64, timeZone string) Location {
+	return Location{
+		City:      city,
+		Latitude:  math.Round(latitude*10)/10 + 0,
+		Longitude: math.Round(longitude*10)/10 + 0,
+		TimeZone:  timeZone,
+	}
+}
+
+// Key tells calendars apart by time zone too, the same coordinates in
+// another time zone have prayer times of another wall clock.
+func (l Location) Key() string {
+	return fmt.Sprintf("%.1f,%.1f,%s", l.Latitude, l.Longitude, l.TimeZone)
+}
+
+var timeZoneLocations = map[string]Location{
+	"Asia/Jakarta":  NewLocation("Jakarta", -6.2088, 106.8456, "Asia/Jakarta"),
+	"Asia/Makassar": NewLocation("Makassar", -5.1477, 119.4327, "Asia/Makassar"),
+	"Asia/Jayapura": NewLocation("Jayapura", -2.5337, 140.7181, "Asia/Jayapura"),
+}
+
+// FallbackTimeZones returns the time zones that have a fallback location,
+// their calendars are kept even before any user picks them.
+func FallbackTimeZones() []string {
+	timeZones := make([]string, 0, len(timeZoneLocations))
+	for timeZone := range timeZoneLocations {
+		timeZones = append(timeZones, timeZone)
+	}
+
+	sort.Strings(timeZones)
+	return timeZones
+}
+
+var ErrNoTimeZoneLocation = errors.New("time zone has no fallback location")
+
+// GetTimeZoneLocation returns the fallback location for users that only
+// picked a time zone. Time zones outside Indonesia have none, their users
+// must set their own location.
+func GetTimeZoneLocation(timeZone string) (Location, error) {
+	location, ok := timeZoneLocations[timeZone]
+	if !ok {
+		return Location{}, errors.Wrap(ErrNoTimeZoneLocation, timeZone)
+	}
+	return location, nil
+}
+
+type Provider interface {
+	Name() string
+	GetPrayerCalendar(ctx context.Context, location Location, year, month int) (PrayerCalendar, error)
+}
+
+const (
+	AladhanProviderName    = "aladhan"
+	CalculatorProviderName = "calculator"
+	StubProviderName       = "stub"
+)
+
+func NewProvider(name string) (Provider, error) {
+	switch name {
+	case AladhanProviderName:
+		return AladhanProvider{}, nil
+	case CalculatorProviderName:
+		return CalculatorProvider{Params: KemenagCalculationParams}, nil
+	case StubProviderName:
+		return StubProvider{}, nil
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown prayer time provider: %s", name))
+	}
+}
+
+type AladhanProvider struct{}
+
+func (AladhanProvider) Name() string {
+	return AladhanProviderName
+}
+
+// Method 20 is the Kemenag RI calculation method on Aladhan.
+func makeAladhanURL(year, month int, location Location) string {
+	return fmt.Sprintf(
+		"https://api.aladhan.com/v1/calendar/%d/%d?latitude=%f&longitude=%f&method=20&timezonestring=%s",
+		year,
+		month,
+		location.Latitude,
+		location.Longitude,
+		url.QueryEscape(location.TimeZone),
+	)
+}
+
+func (AladhanProvider) GetPrayerCalendar(ctx context.Context, location Location, year, month int) (PrayerCalendar, error) {
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load time zone location")
+	}
+
+	prayerCalendar, err := GetAladhanPrayerCalendar(ctx, makeAladhanURL(year, month, location))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get aladhan prayer calendar")
+	}
+
+	parsedPrayerCalendar, err := ParseAladhanPrayerCalendar(prayerCalendar, timeZone)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse aladhan prayer calendar")
+	}
+
+	return parsedPrayerCalendar, nil
+}
+
+type CalculatorProvider struct {
+	Params CalculationParams
+}
+
+func (CalculatorProvider) Name() string {
+	return CalculatorProviderName
+}
+
+func (p CalculatorProvider) GetPrayerCalendar(_ context.Context, location Location, year, month int) (PrayerCalendar, error) {
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load time zone location")
+	}
+
+	return CalculatePrayerCalendar(location.Latitude, location.Longitude, year, month, timeZone, p.Params)
+}
+
+// StubProvider serves the same fixed times every day so the worker can run
+// without network access or real coordinates. The times are on the standard
+// time of the time zone all year round, daylight saving would move them by
+// an hour overnight and fail the calendar validation.
+type StubProvider struct{}
+
+func (StubProvider) Name() string {
+	return StubProviderName
+}
+
+var stubPrayerTimes = []struct {
+	name   string
+	hour   int
+	minute int
+}{
+	{name: SubuhPrayerName, hour: 4, minute: 30},
+	{name: SunriseTimeName, hour: 5, minute: 45},
+	{name: ZuhurPrayerName, hour: 12, minute: 0},
+	{name: AsarPrayerName, hour: 15, minute: 15},
+	{name: MagribPrayerName, hour: 18, minute: 0},
+	{name: IsyaPrayerName, hour: 19, minute: 15},
+}
+
+func (StubProvider) GetPrayerCalendar(_ context.Context, location Location, year, month int) (PrayerCalendar, error) {
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load time zone location")
+	}
+
+	standardTime := getStandardTime(timeZone, year)
+	numOfDays := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, timeZone).Day()
+	prayerCalendar := make(PrayerCalendar, numOfDays)
+	for i := range prayerCalendar {
+		prayers := make(Prayers, len(stubPrayerTimes))
+		for j, v := range stubPrayerTimes {
+			prayers[j] = Prayer{
+				Name:     v.name,
+				UnixTime: time.Date(year, time.Month(month), i+1, v.hour, v.minute, 0, 0, standardTime).Unix(),
+			}
+		}
+		prayerCalendar[i] = prayers
+	}
+
+	return prayerCalendar, nil
+}
+
+// getStandardTime returns the offset of the time zone without daylight
+// saving, the smaller of its offsets in january and july.
+func getStandardTime(timeZone *time.Location, year int) *time.Location {
+	_, januaryOffset := time.Date(year, time.January, 1, 0, 0, 0, 0, timeZone).Zone()
+	_, julyOffset := time.Date(year, time.July, 1, 0, 0, 0, 0, timeZone).Zone()
+	return time.FixedZone(timeZone.String(), min(januaryOffset, julyOffset))
+}
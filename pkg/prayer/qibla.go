@@ -0,0 +1,36 @@
+package prayer
+
+import "math"
+
+const (
+	KaabaLatitude  = 21.422487
+	KaabaLongitude = 39.826206
+	// earthRadius is the mean radius in kilometers.
+	earthRadius = 6371.0088
+)
+
+type Qibla struct {
+	// Direction is the initial great-circle bearing to the Kaaba in degrees
+	// clockwise from true north.
+	Direction float64
+	// Distance is the great-circle distance to the Kaaba in kilometers.
+	Distance float64
+}
+
+func GetQibla(latitude, longitude float64) Qibla {
+	lat1 := degToRad(latitude)
+	lat2 := degToRad(KaabaLatitude)
+	deltaLon := degToRad(KaabaLongitude - longitude)
+
+	y := math.Sin(deltaLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(deltaLon)
+	direction := fixAngle(radToDeg(math.Atan2(y, x)))
+
+	// Haversine stays accurate for the short distances near the Kaaba,
+	// where the spherical law of cosines loses precision.
+	deltaLat := lat2 - lat1
+	a := math.Pow(math.Sin(deltaLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(deltaLon/2), 2)
+	distance := 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+
+	return Qibla{Direction: direction, Distance: distance}
+}
@@ -0,0 +1,53 @@
+package prayer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGetQibla(t *testing.T) {
+	// Reference bearings and distances of the cities, rounded from published
+	// qibla calculators.
+	tests := []struct {
+		city      string
+		latitude  float64
+		longitude float64
+		direction float64
+		distance  float64
+	}{
+		{city: "Jakarta", latitude: -6.2088, longitude: 106.8456, direction: 295.15, distance: 7920},
+		{city: "London", latitude: 51.5074, longitude: -0.1278, direction: 118.99, distance: 4794},
+		{city: "New York", latitude: 40.7128, longitude: -74.0060, direction: 58.48, distance: 10306},
+		{city: "Sydney", latitude: -33.8688, longitude: 151.2093, direction: 277.50, distance: 13236},
+		{city: "Medina", latitude: 24.4672, longitude: 39.6111, direction: 176.24, distance: 339},
+	}
+
+	const (
+		directionTolerance = 0.5
+		distanceTolerance  = 0.01
+	)
+
+	for _, test := range tests {
+		t.Run(test.city, func(t *testing.T) {
+			qibla := GetQibla(test.latitude, test.longitude)
+			if math.Abs(qibla.Direction-test.direction) > directionTolerance {
+				t.Errorf("direction = %.2f, want %.2f", qibla.Direction, test.direction)
+			}
+
+			if math.Abs(qibla.Distance-test.distance) > test.distance*distanceTolerance {
+				t.Errorf("distance = %.1f km, want %.1f km", qibla.Distance, test.distance)
+			}
+		})
+	}
+}
+
+func TestGetQiblaAtKaaba(t *testing.T) {
+	qibla := GetQibla(KaabaLatitude, KaabaLongitude)
+	if math.IsNaN(qibla.Direction) || qibla.Direction < 0 || qibla.Direction >= 360 {
+		t.Errorf("direction = %f, want a bearing in [0, 360)", qibla.Direction)
+	}
+
+	if qibla.Distance > 0.001 {
+		t.Errorf("distance = %f km, want 0", qibla.Distance)
+	}
+}
@@ -0,0 +1,104 @@
+package prayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	quietTimeLayout = "15:04"
+	MaxQuietWindows = 5
+)
+
+// QuietWindow is a daily window in the user's local time, "HH:MM" each. A
+// window whose end is before its start spans midnight.
+type QuietWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+func minuteOfDay(value string) (int, error) {
+	t, err := time.Parse(quietTimeLayout, value)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse quiet window time")
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func ValidateQuietWindows(windows []QuietWindow) error {
+	if len(windows) > MaxQuietWindows {
+		return errors.New(fmt.Sprintf("too many quiet windows: %d", len(windows)))
+	}
+
+	for _, window := range windows {
+		start, err := minuteOfDay(window.Start)
+		if err != nil {
+			return err
+		}
+
+		end, err := minuteOfDay(window.End)
+		if err != nil {
+			return err
+		}
+
+		if start == end {
+			return errors.New(fmt.Sprintf("quiet window is empty: %s-%s", window.Start, window.End))
+		}
+	}
+	return nil
+}
+
+// DoNotDisturb holds when a user does not want to be reminded. Reminders
+// still run in the meantime so the reminder chain carries on.
+type DoNotDisturb struct {
+	QuietWindows []QuietWindow
+	PausedUntil  time.Time
+}
+
+func NewDoNotDisturb(quietWindows []byte, pausedUntil time.Time) (DoNotDisturb, error) {
+	doNotDisturb := DoNotDisturb{PausedUntil: pausedUntil}
+	if len(quietWindows) == 0 {
+		return doNotDisturb, nil
+	}
+
+	err := json.Unmarshal(quietWindows, &doNotDisturb.QuietWindows)
+	if err != nil {
+		return DoNotDisturb{}, errors.Wrap(err, "failed to unmarshal quiet windows")
+	}
+
+	return doNotDisturb, nil
+}
+
+// IsActive reports whether t, in the user's time zone, is paused or falls
+// in one of the quiet windows.
+func (d DoNotDisturb) IsActive(t time.Time) bool {
+	if t.Before(d.PausedUntil) {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, window := range d.QuietWindows {
+		start, err := minuteOfDay(window.Start)
+		if err != nil {
+			continue
+		}
+
+		end, err := minuteOfDay(window.End)
+		if err != nil {
+			continue
+		}
+
+		if start < end && minute >= start && minute < end {
+			return true
+		}
+
+		if start > end && (minute >= start || minute < end) {
+			return true
+		}
+	}
+
+	return false
+}
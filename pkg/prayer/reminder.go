@@ -0,0 +1,51 @@
+package prayer
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	MaxReminderLeadMinutes     = 60
+	DefaultLastReminderPercent = 75
+	MaxLastReminderPercent     = 95
+)
+
+// ReminderPreference is how a user is reminded of one fardhu prayer. A
+// zero LastReminderPercent turns the last reminder off.
+type ReminderPreference struct {
+	Enabled             bool
+	LeadMinutes         int
+	LastReminderPercent int
+	// Channel is tried before the user's notification channels when set.
+	Channel string
+}
+
+var DefaultReminderPreference = ReminderPreference{
+	Enabled:             true,
+	LastReminderPercent: DefaultLastReminderPercent,
+}
+
+func (p ReminderPreference) Validate() error {
+	if p.LeadMinutes < 0 || p.LeadMinutes > MaxReminderLeadMinutes {
+		return errors.New(fmt.Sprintf("lead minutes is out of range: %d", p.LeadMinutes))
+	}
+
+	if p.LastReminderPercent < 0 || p.LastReminderPercent > MaxLastReminderPercent {
+		return errors.New(fmt.Sprintf("last reminder percent is out of range: %d", p.LastReminderPercent))
+	}
+	return nil
+}
+
+// ReminderUnixTime returns when the reminder of a prayer starting at
+// prayerUnixTime goes out.
+func (p ReminderPreference) ReminderUnixTime(prayerUnixTime int64) int64 {
+	return prayerUnixTime - int64(p.LeadMinutes*60)
+}
+
+// LastReminderUnixTime returns the moment the given percentage of the
+// prayer window has passed.
+func (p ReminderPreference) LastReminderUnixTime(startUnixTime, endUnixTime int64) int64 {
+	return startUnixTime + (endUnixTime-startUnixTime)*int64(p.LastReminderPercent)/100
+}
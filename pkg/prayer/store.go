@@ -0,0 +1,225 @@
+package prayer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// A location's calendar is a redis hash of date to that day's prayers, so a
+// lookup never has to care which month the date belongs to.
+func MakePrayerCalendarKey(locationKey string) string {
+	return fmt.Sprintf("prayer:dates:%s", locationKey)
+}
+
+func makeDateField(date time.Time) string {
+	return date.Format(time.DateOnly)
+}
+
+// CalendarRepository is the durable copy of the calendars, redis only
+// caches it.
+type CalendarRepository interface {
+	GetPrayerCalendar(ctx context.Context, location Location, from, to time.Time) (PrayerCalendar, error)
+}
+
+type CalendarStore struct {
+	redisClient *redis.Client
+	repository  CalendarRepository
+}
+
+func NewCalendarStore(redisClient *redis.Client, repository CalendarRepository) CalendarStore {
+	return CalendarStore{redisClient: redisClient, repository: repository}
+}
+
+func (s CalendarStore) SetPrayerCalendar(ctx context.Context, location Location, prayerCalendar PrayerCalendar) error {
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		return errors.Wrap(err, "failed to load time zone location")
+	}
+
+	fields := make(map[string]interface{}, len(prayerCalendar))
+	for _, prayers := range prayerCalendar {
+		zuhur, ok := prayers.Get(ZuhurPrayerName)
+		if !ok {
+			continue
+		}
+
+		prayersJSON, err := json.Marshal(prayers)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal prayers")
+		}
+
+		date := time.Unix(zuhur.UnixTime, 0).In(timeZone)
+		fields[makeDateField(date)] = prayersJSON
+	}
+
+	err = s.redisClient.HSet(ctx, MakePrayerCalendarKey(location.Key()), fields).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to set prayer calendar")
+	}
+
+	return nil
+}
+
+// PruneBefore removes every stored day before the given date.
+func (s CalendarStore) PruneBefore(ctx context.Context, location Location, date time.Time) error {
+	key := MakePrayerCalendarKey(location.Key())
+	fields, err := s.redisClient.HKeys(ctx, key).Result()
+	if err != nil {
+		return errors.Wrap(err, "failed to get prayer calendar dates")
+	}
+
+	cutoff := makeDateField(date)
+	staleFields := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field < cutoff {
+			staleFields = append(staleFields, field)
+		}
+	}
+
+	if len(staleFields) == 0 {
+		return nil
+	}
+
+	err = s.redisClient.HDel(ctx, key, staleFields...).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to delete stale prayer calendar dates")
+	}
+
+	return nil
+}
+
+func (s CalendarStore) HasPrayerCalendar(ctx context.Context, location Location, year, month int) (bool, error) {
+	firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstDay.AddDate(0, 1, -1)
+
+	values, err := s.redisClient.HMGet(
+		ctx,
+		MakePrayerCalendarKey(location.Key()),
+		makeDateField(firstDay),
+		makeDateField(lastDay),
+	).Result()
+
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get prayer calendar dates")
+	}
+
+	for _, value := range values {
+		if value == nil {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// WarmPrayerCalendar copies a month from the repository into redis. It
+// returns redis.Nil when the repository does not hold the whole month.
+func (s CalendarStore) WarmPrayerCalendar(ctx context.Context, location Location, year, month int) error {
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		return errors.Wrap(err, "failed to load time zone location")
+	}
+
+	firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, timeZone)
+	lastDay := firstDay.AddDate(0, 1, -1)
+
+	prayerCalendar, err := s.repository.GetPrayerCalendar(ctx, location, firstDay, lastDay)
+	if err != nil {
+		return errors.Wrap(err, "failed to get prayer calendar from repository")
+	}
+
+	if len(prayerCalendar) != lastDay.Day() {
+		return redis.Nil
+	}
+
+	return s.SetPrayerCalendar(ctx, location, prayerCalendar)
+}
+
+func (s CalendarStore) getPrayersForDate(
+	ctx context.Context,
+	location Location,
+	adjustment Adjustment,
+	date time.Time,
+) (Prayers, error) {
+	key := MakePrayerCalendarKey(location.Key())
+	prayersJSON, err := s.redisClient.HGet(ctx, key, makeDateField(date)).Result()
+	if errors.Is(err, redis.Nil) && s.repository != nil {
+		err = s.WarmPrayerCalendar(ctx, location, date.Year(), int(date.Month()))
+		if err != nil {
+			return nil, err
+		}
+
+		prayersJSON, err = s.redisClient.HGet(ctx, key, makeDateField(date)).Result()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var prayers Prayers
+	err = json.Unmarshal([]byte(prayersJSON), &prayers)
+	if err != nil {
+		return nil, err
+	}
+
+	return WithSunnahTimes(prayers).Adjust(location, adjustment), nil
+}
+
+// GetPrayersForDate returns the prayers of the date as seen in the location's
+// time zone, warming redis from the repository on a miss. It returns
+// redis.Nil when neither holds the date.
+func (s CalendarStore) GetPrayersForDate(
+	ctx context.Context,
+	location Location,
+	adjustment Adjustment,
+	date time.Time,
+) (Prayers, error) {
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load time zone location")
+	}
+
+	return s.getPrayersForDate(ctx, location, adjustment, date.In(timeZone))
+}
+
+// NextPrayerAfter returns the first fardhu prayer strictly after t.
+func (s CalendarStore) NextPrayerAfter(
+	ctx context.Context,
+	location Location,
+	adjustment Adjustment,
+	t time.Time,
+) (Prayer, error) {
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		return Prayer{}, errors.Wrap(err, "failed to load time zone location")
+	}
+
+	date := t.In(timeZone)
+	todayPrayers, err := s.getPrayersForDate(ctx, location, adjustment, date)
+	if err != nil {
+		return Prayer{}, err
+	}
+
+	for _, prayer := range todayPrayers.Fardhu() {
+		if prayer.UnixTime > t.Unix() {
+			return prayer, nil
+		}
+	}
+
+	tomorrowPrayers, err := s.getPrayersForDate(ctx, location, adjustment, date.AddDate(0, 0, 1))
+	if err != nil {
+		return Prayer{}, err
+	}
+
+	subuh, ok := tomorrowPrayers.Get(SubuhPrayerName)
+	if !ok {
+		return Prayer{}, errors.New("missing subuh prayer")
+	}
+
+	return subuh, nil
+}
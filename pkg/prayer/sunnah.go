@@ -0,0 +1,105 @@
+package prayer
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// Kemenag sets imsak 10 minutes before subuh.
+	ImsakOffset = 10 * time.Minute
+	// Dhuha starts once the sun is a spear's length high.
+	DhuhaStartOffset = 15 * time.Minute
+	// The sun stands at its zenith shortly before zuhur, dhuha ends there.
+	IstiwaDuration = 10 * time.Minute
+	// The sun turns yellow shortly before magrib.
+	SunsetDuration = 15 * time.Minute
+)
+
+var (
+	AfterSubuhMakruhName = "AfterSubuh"
+	SunriseMakruhName    = "Sunrise"
+	IstiwaMakruhName     = "Istiwa"
+	AfterAsarMakruhName  = "AfterAsar"
+	SunsetMakruhName     = "Sunset"
+)
+
+func GetImsakTime(subuhPrayer Prayer) Prayer {
+	return Prayer{
+		Name:     ImsakTimeName,
+		UnixTime: subuhPrayer.UnixTime - int64(ImsakOffset.Seconds()),
+	}
+}
+
+// WithSunnahTimes adds imsak, the dhuha window and the start of the last
+// third of the night before subuh for tahajud. Times already present are
+// kept, and the result is sorted by time.
+func WithSunnahTimes(prayers Prayers) Prayers {
+	subuh, hasSubuh := prayers.Get(SubuhPrayerName)
+	sunrise, hasSunrise := prayers.Get(SunriseTimeName)
+	zuhur, hasZuhur := prayers.Get(ZuhurPrayerName)
+	magrib, hasMagrib := prayers.Get(MagribPrayerName)
+	if !hasSubuh || !hasSunrise || !hasZuhur || !hasMagrib {
+		return prayers
+	}
+
+	// The night before subuh starts at yesterday's magrib, today's is close
+	// enough to stand in for it.
+	night := subuh.UnixTime + int64((24 * time.Hour).Seconds()) - magrib.UnixTime
+	sunnahTimes := Prayers{
+		GetImsakTime(subuh),
+		{Name: DhuhaStartTimeName, UnixTime: sunrise.UnixTime + int64(DhuhaStartOffset.Seconds())},
+		{Name: DhuhaEndTimeName, UnixTime: zuhur.UnixTime - int64(IstiwaDuration.Seconds())},
+		{Name: TahajudTimeName, UnixTime: subuh.UnixTime - night/3},
+	}
+
+	result := make(Prayers, len(prayers), len(prayers)+len(sunnahTimes))
+	copy(result, prayers)
+	for _, sunnahTime := range sunnahTimes {
+		if _, ok := prayers.Get(sunnahTime.Name); !ok {
+			result = append(result, sunnahTime)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].UnixTime < result[j].UnixTime
+	})
+
+	return result
+}
+
+func (c PrayerCalendar) WithSunnahTimes() PrayerCalendar {
+	prayerCalendar := make(PrayerCalendar, len(c))
+	for i, prayers := range c {
+		prayerCalendar[i] = WithSunnahTimes(prayers)
+	}
+	return prayerCalendar
+}
+
+type TimeWindow struct {
+	Name  string
+	Start int64
+	End   int64
+}
+
+// MakruhWindows returns the windows in which voluntary prayers are
+// discouraged.
+func (p Prayers) MakruhWindows() []TimeWindow {
+	subuh, hasSubuh := p.Get(SubuhPrayerName)
+	sunrise, hasSunrise := p.Get(SunriseTimeName)
+	zuhur, hasZuhur := p.Get(ZuhurPrayerName)
+	asar, hasAsar := p.Get(AsarPrayerName)
+	magrib, hasMagrib := p.Get(MagribPrayerName)
+	if !hasSubuh || !hasSunrise || !hasZuhur || !hasAsar || !hasMagrib {
+		return nil
+	}
+
+	sunsetStart := magrib.UnixTime - int64(SunsetDuration.Seconds())
+	return []TimeWindow{
+		{Name: AfterSubuhMakruhName, Start: subuh.UnixTime, End: sunrise.UnixTime},
+		{Name: SunriseMakruhName, Start: sunrise.UnixTime, End: sunrise.UnixTime + int64(DhuhaStartOffset.Seconds())},
+		{Name: IstiwaMakruhName, Start: zuhur.UnixTime - int64(IstiwaDuration.Seconds()), End: zuhur.UnixTime},
+		{Name: AfterAsarMakruhName, Start: asar.UnixTime, End: sunsetStart},
+		{Name: SunsetMakruhName, Start: sunsetStart, End: magrib.UnixTime},
+	}
+}
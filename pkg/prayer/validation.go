@@ -0,0 +1,112 @@
+package prayer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var prayerOrder = []string{
+	SubuhPrayerName,
+	SunriseTimeName,
+	ZuhurPrayerName,
+	AsarPrayerName,
+	MagribPrayerName,
+	IsyaPrayerName,
+}
+
+// Prayer times move by a few minutes a day at most, anything bigger means
+// the provider served another place or another month.
+const (
+	MaxDailyDrift    = 10 * time.Minute
+	MaxProviderDrift = 10 * time.Minute
+)
+
+var ErrInvalidPrayerCalendar = errors.New("invalid prayer calendar")
+
+func invalidPrayerCalendar(format string, args ...interface{}) error {
+	return errors.Wrap(ErrInvalidPrayerCalendar, fmt.Sprintf(format, args...))
+}
+
+func ValidatePrayerCalendar(prayerCalendar PrayerCalendar, location Location, year, month int) error {
+	timeZone, err := time.LoadLocation(location.TimeZone)
+	if err != nil {
+		return errors.Wrap(err, "failed to load time zone location")
+	}
+
+	numOfDays := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, timeZone).Day()
+	if len(prayerCalendar) != numOfDays {
+		return invalidPrayerCalendar("expected %d days, got %d", numOfDays, len(prayerCalendar))
+	}
+
+	for i, prayers := range prayerCalendar {
+		day := i + 1
+		for j, name := range prayerOrder {
+			prayer, ok := prayers.Get(name)
+			if !ok {
+				return invalidPrayerCalendar("day %d: missing %s", day, name)
+			}
+
+			// Isya may pass midnight at high latitudes, so only zuhur has to
+			// fall on the day itself.
+			prayerTime := time.Unix(prayer.UnixTime, 0).In(timeZone)
+			if name == ZuhurPrayerName &&
+				(prayerTime.Year() != year || int(prayerTime.Month()) != month || prayerTime.Day() != day) {
+				return invalidPrayerCalendar("day %d: %s falls on %s", day, name, prayerTime.Format(time.DateOnly))
+			}
+
+			if j > 0 {
+				previousPrayer, _ := prayers.Get(prayerOrder[j-1])
+				if prayer.UnixTime <= previousPrayer.UnixTime {
+					return invalidPrayerCalendar("day %d: %s is not after %s", day, name, previousPrayer.Name)
+				}
+			}
+
+			if i == 0 {
+				continue
+			}
+
+			// Measured on unix time so daylight saving shifts are not drift.
+			yesterdayPrayer, _ := prayerCalendar[i-1].Get(name)
+			drift := time.Duration(prayer.UnixTime-yesterdayPrayer.UnixTime)*time.Second - 24*time.Hour
+			if drift < 0 {
+				drift = -drift
+			}
+
+			if drift > MaxDailyDrift {
+				return invalidPrayerCalendar("day %d: %s drifted %s from the day before", day, name, drift)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ComparePrayerCalendars checks that two calendars of the same month agree
+// within the given tolerance.
+func ComparePrayerCalendars(prayerCalendar, otherPrayerCalendar PrayerCalendar, tolerance time.Duration) error {
+	if len(prayerCalendar) != len(otherPrayerCalendar) {
+		return invalidPrayerCalendar("expected %d days, got %d", len(otherPrayerCalendar), len(prayerCalendar))
+	}
+
+	for i, prayers := range prayerCalendar {
+		for _, prayer := range prayers {
+			otherPrayer, ok := otherPrayerCalendar[i].Get(prayer.Name)
+			if !ok {
+				continue
+			}
+
+			diff := time.Duration(prayer.UnixTime-otherPrayer.UnixTime) * time.Second
+			if diff < 0 {
+				diff = -diff
+			}
+
+			if diff > tolerance {
+				return invalidPrayerCalendar("day %d: %s differs by %s", i+1, prayer.Name, diff)
+			}
+		}
+	}
+
+	return nil
+}
@@ -5,16 +5,18 @@ import (
 	"fmt"
 
 	"github.com/hibiken/asynq"
+	"github.com/mdayat/demi-masa/pkg/prayer"
 	"github.com/pkg/errors"
 )
 
 const (
 	TypeUserDowngrade      = "user:downgrade"
-	TypePrayerReminder     = "prayer:remind"
-	TypeLastPrayerReminder = "prayer:last_remind"
+	TypePrayerFanout       = "prayer:fanout"
 	TypePrayerRenewal      = "prayer:renew"
+	TypePrayerCalendarInit = "prayer:init"
 	TypePrayerUpdate       = "prayer:update"
 	TypeTaskRemoval        = "task:remove"
+	TypeReminderReconcile  = "reminder:reconcile"
 )
 
 const (
@@ -39,99 +41,125 @@ func NewUserDowngradeTask(payload UserDowngradePayload) (*asynq.Task, error) {
 	), nil
 }
 
-func MakePrayerReminderTaskID(userID string, prayerName string) string {
-	return fmt.Sprintf("%s:%s", userID, prayerName)
+// MakePrayerFanoutTaskID keys a run by where it resumes the prayer's
+// reminders, the first run of a prayer resumes from zero.
+func MakePrayerFanoutTaskID(locationKey string, prayerName string, prayerUnixTime int64, fromUnixTime int64) string {
+	return fmt.Sprintf("%s:%s:%s:%d:%d", TypePrayerFanout, locationKey, prayerName, prayerUnixTime, fromUnixTime)
 }
 
-type PrayerReminderPayload struct {
-	UserID         string
+// PrayerFanoutPayload is one run over the users of a location, sending the
+// reminders of the prayer that fall between FromUnixTime and RunUnixTime.
+type PrayerFanoutPayload struct {
+	Location       prayer.Location
 	PrayerName     string
 	PrayerUnixTime int64
-	IsLastDay      bool
+	FromUnixTime   int64
+	RunUnixTime    int64
 }
 
-func NewPrayerReminderTask(payload PrayerReminderPayload) (*asynq.Task, error) {
+func NewPrayerFanoutTask(payload PrayerFanoutPayload) (*asynq.Task, error) {
 	bytes, err := json.Marshal(payload)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to marshal prayer reminder task payload")
+		return nil, errors.Wrap(err, "failed to marshal prayer fanout task payload")
 	}
 
 	return asynq.NewTask(
-		TypePrayerReminder,
+		TypePrayerFanout,
 		bytes,
-		asynq.TaskID(MakePrayerReminderTaskID(payload.UserID, payload.PrayerName)),
+		asynq.TaskID(MakePrayerFanoutTaskID(
+			payload.Location.Key(),
+			payload.PrayerName,
+			payload.PrayerUnixTime,
+			payload.FromUnixTime,
+		)),
 		asynq.MaxRetry(3),
 	), nil
 }
 
-func MakeLastPrayerReminderTaskID(userID string, prayerName string) string {
-	return fmt.Sprintf("%s:%s:last", userID, prayerName)
+func MakePrayerRenewalTaskID(locationKey string, unixTime int64) string {
+	return fmt.Sprintf("%s:%s:%d", TypePrayerRenewal, locationKey, unixTime)
 }
 
-type LastPrayerReminderPayload struct {
-	UserID     string
-	PrayerName string
+type PrayerRenewalTask struct {
+	Location prayer.Location
 }
 
-func NewLastPrayerReminderTask(payload LastPrayerReminderPayload) (*asynq.Task, error) {
+func NewPrayerRenewalTask(payload PrayerRenewalTask) (*asynq.Task, error) {
 	bytes, err := json.Marshal(payload)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to marshal last prayer reminder task payload")
+		return nil, errors.Wrap(err, "failed to marshal prayer renewal task payload")
 	}
 
 	return asynq.NewTask(
-		TypeLastPrayerReminder,
+		TypePrayerRenewal,
 		bytes,
-		asynq.TaskID(MakeLastPrayerReminderTaskID(payload.UserID, payload.PrayerName)),
 		asynq.MaxRetry(3),
 	), nil
 }
 
-func MakePrayerRenewalTaskID(timeZone string, month int) string {
-	return fmt.Sprintf("%s:%s:%d", TypePrayerRenewal, timeZone, month)
+func MakePrayerCalendarInitTaskID(locationKey string) string {
+	return fmt.Sprintf("%s:%s", TypePrayerCalendarInit, locationKey)
 }
 
-type PrayerRenewalTask struct {
-	TimeZone string
-	Month    int
+type PrayerCalendarInitPayload struct {
+	Location prayer.Location
 }
 
-func NewPrayerRenewalTask(payload PrayerRenewalTask) (*asynq.Task, error) {
+func NewPrayerCalendarInitTask(payload PrayerCalendarInitPayload) (*asynq.Task, error) {
 	bytes, err := json.Marshal(payload)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to marshal prayer renewal task payload")
+		return nil, errors.Wrap(err, "failed to marshal prayer calendar init task payload")
 	}
 
 	return asynq.NewTask(
-		TypePrayerRenewal,
+		TypePrayerCalendarInit,
 		bytes,
-		asynq.TaskID(MakePrayerRenewalTaskID(payload.TimeZone, payload.Month)),
+		asynq.TaskID(MakePrayerCalendarInitTaskID(payload.Location.Key())),
 		asynq.MaxRetry(3),
 	), nil
 }
 
-func MakeTaskRemovalTaskID(day int) string {
-	return fmt.Sprintf("%s:%d", TypeTaskRemoval, day)
+func MakeTaskRemovalTaskID(unixTime int64) string {
+	return fmt.Sprintf("%s:%d", TypeTaskRemoval, unixTime)
 }
 
-func NewTaskRemovalTask(day int) (*asynq.Task, error) {
+func NewTaskRemovalTask() (*asynq.Task, error) {
 	return asynq.NewTask(
 		TypeTaskRemoval,
 		nil,
-		asynq.TaskID(MakeTaskRemovalTaskID(day)),
 		asynq.MaxRetry(3),
 	), nil
 }
 
-func MakePrayerUpdateTaskID(day int) string {
-	return fmt.Sprintf("%s:%d", TypePrayerUpdate, day)
+func MakePrayerUpdateTaskID(timeZone string, unixTime int64) string {
+	return fmt.Sprintf("%s:%s:%d", TypePrayerUpdate, timeZone, unixTime)
+}
+
+type PrayerUpdatePayload struct {
+	TimeZone string
 }
 
-func NewPrayerUpdateTask(day int) (*asynq.Task, error) {
+func NewPrayerUpdateTask(payload PrayerUpdatePayload) (*asynq.Task, error) {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal prayer update task payload")
+	}
+
 	return asynq.NewTask(
 		TypePrayerUpdate,
+		bytes,
+		asynq.MaxRetry(3),
+	), nil
+}
+
+func MakeReminderReconcileTaskID(unixTime int64) string {
+	return fmt.Sprintf("%s:%d", TypeReminderReconcile, unixTime)
+}
+
+func NewReminderReconcileTask() (*asynq.Task, error) {
+	return asynq.NewTask(
+		TypeReminderReconcile,
 		nil,
-		asynq.TaskID(MakePrayerUpdateTaskID(day)),
 		asynq.MaxRetry(3),
 	), nil
 }
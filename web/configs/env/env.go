@@ -7,15 +7,20 @@ import (
 )
 
 var (
-	DATABASE_URL         string
-	TWILIO_ACCOUNT_SID   string
-	TWILIO_AUTH_TOKEN    string
-	TWILIO_SENDER        string
-	REDIS_URL            string
-	TRIPAY_MERCHANT_CODE string
-	TRIPAY_API_KEY       string
-	TRIPAY_PRIVATE_KEY   string
-	ALLOWED_ORIGINS      string
+	DATABASE_URL               string
+	TWILIO_ACCOUNT_SID         string
+	TWILIO_AUTH_TOKEN          string
+	TWILIO_SENDER              string
+	REDIS_URL                  string
+	TRIPAY_MERCHANT_CODE       string
+	TRIPAY_API_KEY             string
+	TRIPAY_PRIVATE_KEY         string
+	ALLOWED_ORIGINS            string
+	HIJRI_OFFSETS              string
+	NOTIFICATION_CHANNELS      string
+	TWILIO_SMS_SENDER          string
+	TWILIO_STATUS_CALLBACK_URL string
+	TWILIO_INBOUND_URL         string
 )
 
 func Init() error {
@@ -33,6 +38,11 @@ func Init() error {
 	TRIPAY_API_KEY = os.Getenv("TRIPAY_API_KEY")
 	TRIPAY_PRIVATE_KEY = os.Getenv("TRIPAY_PRIVATE_KEY")
 	ALLOWED_ORIGINS = os.Getenv("ALLOWED_ORIGINS")
+	HIJRI_OFFSETS = os.Getenv("HIJRI_OFFSETS")
+	NOTIFICATION_CHANNELS = os.Getenv("NOTIFICATION_CHANNELS")
+	TWILIO_SMS_SENDER = os.Getenv("TWILIO_SMS_SENDER")
+	TWILIO_STATUS_CALLBACK_URL = os.Getenv("TWILIO_STATUS_CALLBACK_URL")
+	TWILIO_INBOUND_URL = os.Getenv("TWILIO_INBOUND_URL")
 
 	return nil
 }
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/mdayat/demi-masa/web/repository"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	apiKeyPrefix            = "dm_"
+	maxAPIKeysPerUser       = 5
+	defaultAPIKeyDailyQuota = 1000
+)
+
+type apiKeyRespBody struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Key        string    `json:"key,omitempty"`
+	DailyQuota int32     `json:"daily_quota"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func getAPIKeysHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	apiKeys, err := services.Queries.GetAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get api keys by user id")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respBody := make([]apiKeyRespBody, len(apiKeys))
+	for i, v := range apiKeys {
+		apiKeyID, err := v.ID.Value()
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get api key UUID from pgtype.UUID")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		respBody[i] = apiKeyRespBody{
+			ID:         fmt.Sprintf("%s", apiKeyID),
+			Name:       v.Name,
+			DailyQuota: v.DailyQuota,
+			CreatedAt:  v.CreatedAt.Time,
+		}
+	}
+
+	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusOK, Data: &respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func createAPIKeyHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		Name string `json:"name" validate:"required,max=255"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	apiKeyCount, err := services.Queries.CountAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to count api keys by user id")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if apiKeyCount >= maxAPIKeysPerUser {
+		err := errors.New(fmt.Sprintf("user already has %d api keys", apiKeyCount))
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusConflict).Send()
+		http.Error(res, http.StatusText(http.StatusConflict), http.StatusConflict)
+		return
+	}
+
+	token, err := generateSecretToken()
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to generate api key")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	key := apiKeyPrefix + token
+	apiKey, err := services.Queries.CreateAPIKey(ctx, repository.CreateAPIKeyParams{
+		UserID:     userID,
+		Name:       body.Name,
+		KeyHash:    makeSecretTokenHash(key),
+		DailyQuota: defaultAPIKeyDailyQuota,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to create api key")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	apiKeyID, err := apiKey.ID.Value()
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get api key UUID from pgtype.UUID")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respBody := apiKeyRespBody{
+		ID:         fmt.Sprintf("%s", apiKeyID),
+		Name:       apiKey.Name,
+		Key:        key,
+		DailyQuota: apiKey.DailyQuota,
+		CreatedAt:  apiKey.CreatedAt.Time,
+	}
+
+	res.Header().Set("Location", fmt.Sprintf("/api-keys/%s", apiKeyID))
+	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusCreated, Data: &respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusCreated).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func deleteAPIKeyHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	apiKeyIDBytes, err := uuid.Parse(chi.URLParam(req, "apiKeyID"))
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("failed to parse api key uuid string to bytes")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	affectedRows, err := services.Queries.DeleteAPIKey(ctx, repository.DeleteAPIKeyParams{
+		ID:     pgtype.UUID{Bytes: apiKeyIDBytes, Valid: true},
+		UserID: userID,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to delete api key")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if affectedRows == 0 {
+		logWithCtx.Error().Caller().Int("status_code", http.StatusNotFound).Msg("api key not found")
+		http.Error(res, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
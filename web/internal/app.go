@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"net/http"
 	"strings"
 	"time"
 
@@ -21,22 +22,53 @@ func InitApp() *chi.Mux {
 	options := cors.Options{
 		AllowedOrigins:   strings.Split(env.ALLOWED_ORIGINS, ","),
 		AllowedMethods:   []string{"GET", "PUT", "POST", "DELETE", "HEAD", "OPTIONS"},
-		AllowedHeaders:   []string{"User-Agent", "Content-Type", "Accept", "Accept-Encoding", "Accept-Language", "Cache-Control", "Connection", "Host", "Origin", "Referer", "Authorization"},
-		ExposedHeaders:   []string{"Content-Length", "Location"},
+		AllowedHeaders:   []string{"User-Agent", "Content-Type", "Accept", "Accept-Encoding", "Accept-Language", "Cache-Control", "Connection", "Host", "Origin", "Referer", "Authorization", "If-None-Match"},
+		ExposedHeaders:   []string{"Content-Length", "Location", "ETag", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}
-	router.Use(cors.Handler(options))
+	router.Use(skipPublicAPI(cors.Handler(options)))
 	router.Use(middleware.Heartbeat("/ping"))
 
 	router.Post("/login", loginHandler)
 	router.Post("/transactions/callback", tripayWebhookHandler)
+	router.Post("/notifications/twilio/status", twilioStatusWebhookHandler)
+	router.Post("/notifications/twilio/inbound", twilioInboundWebhookHandler)
+	router.Get("/calendar/{token}.ics", getCalendarFeedHandler)
+
+	// The public API is called from any origin with an api key instead of
+	// the user's credentials, so it gets a cors policy of its own.
+	publicOptions := cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "HEAD", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Accept-Encoding", "Accept-Language", "Cache-Control", "Content-Type", "X-API-Key", "If-None-Match"},
+		ExposedHeaders:   []string{"Content-Length", "ETag", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
+		AllowCredentials: false,
+		MaxAge:           300,
+	}
+
+	router.Route("/public/v1", func(r chi.Router) {
+		r.Use(cors.Handler(publicOptions))
+		r.Use(authenticateAPIKey)
+		r.Get("/prayer-times", getPublicPrayerTimesHandler)
+	})
 
 	router.Group(func(r chi.Router) {
 		r.Use(authenticate)
 
 		r.Delete("/users/{userID}", deleteUserHandler)
 		r.Put("/users/{userID}/time-zone", updateTimeZoneHandler)
+		r.Put("/users/{userID}/location", updateLocationHandler)
+		r.Put("/users/{userID}/sunnah-reminder", updateSunnahReminderHandler)
+		r.Put("/users/{userID}/prayer-settings", updatePrayerSettingsHandler)
+		r.Put("/users/{userID}/notification-channels", updateNotificationChannelsHandler)
+		r.Put("/users/{userID}/quiet-hours", updateQuietHoursHandler)
+		r.Put("/users/{userID}/locale", updateLocaleHandler)
+
+		r.Post("/devices", registerDeviceHandler)
+		r.Delete("/devices/{token}", unregisterDeviceHandler)
+
+		r.Get("/notifications", getNotificationsHandler)
 
 		r.Post("/otp/generation", generateOTPHandler)
 		r.Post("/otp/verification", verifyOTPHandler)
@@ -51,10 +83,43 @@ func InitApp() *chi.Mux {
 
 		r.Get("/prayers", getPrayersHandler)
 		r.Get("/prayers/today", getTodayPrayersHandler)
+		r.Get("/prayers/today/times", getTodayPrayerTimesHandler)
 		r.Put("/prayers/{prayerID}", updatePrayerHandler)
 
+		r.Get("/qibla", getQiblaHandler)
+
+		r.Get("/reminder-preferences", getReminderPreferencesHandler)
+		r.Put("/reminder-preferences/{prayerName}", updateReminderPreferenceHandler)
+
+		r.Get("/reminder-pause", getReminderPauseHandler)
+		r.Put("/reminder-pause", updateReminderPauseHandler)
+		r.Delete("/reminder-pause", deleteReminderPauseHandler)
+
 		r.Get("/subscription-plans", getSubsPlansHandler)
+
+		r.Post("/calendar-feed", createCalendarFeedHandler)
+		r.Put("/calendar-feed", updateCalendarFeedHandler)
+		r.Delete("/calendar-feed", deleteCalendarFeedHandler)
+
+		r.Get("/api-keys", getAPIKeysHandler)
+		r.Post("/api-keys", createAPIKeyHandler)
+		r.Delete("/api-keys/{apiKeyID}", deleteAPIKeyHandler)
 	})
 
 	return router
 }
+
+// skipPublicAPI leaves the requests of the public API to the middlewares of
+// its own routes.
+func skipPublicAPI(middleware func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withMiddleware := middleware(next)
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if strings.HasPrefix(req.URL.Path, "/public/") {
+				next.ServeHTTP(res, req)
+				return
+			}
+			withMiddleware.ServeHTTP(res, req)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -74,15 +75,25 @@ func loginHandler(res http.ResponseWriter, req *http.Request) {
 	}
 
 	respBody := struct {
-		PhoneNumber   string                       `json:"phone_number,omitempty"`
-		PhoneVerified bool                         `json:"phone_verified"`
-		AccountType   repository.AccountType       `json:"account_type"`
-		TimeZone      repository.IndonesiaTimeZone `json:"time_zone,omitempty"`
+		PhoneNumber          string                 `json:"phone_number,omitempty"`
+		PhoneVerified        bool                   `json:"phone_verified"`
+		AccountType          repository.AccountType `json:"account_type"`
+		TimeZone             string                 `json:"time_zone,omitempty"`
+		AsrMethod            repository.AsrMethod   `json:"asr_method"`
+		PrayerOffsets        json.RawMessage        `json:"prayer_offsets"`
+		NotificationChannels json.RawMessage        `json:"notification_channels"`
+		QuietWindows         json.RawMessage        `json:"quiet_windows"`
+		Locale               string                 `json:"locale"`
 	}{
-		PhoneNumber:   user.PhoneNumber.String,
-		PhoneVerified: user.PhoneVerified,
-		AccountType:   user.AccountType,
-		TimeZone:      user.TimeZone.IndonesiaTimeZone,
+		PhoneNumber:          user.PhoneNumber.String,
+		PhoneVerified:        user.PhoneVerified,
+		AccountType:          user.AccountType,
+		TimeZone:             user.TimeZone.String,
+		AsrMethod:            user.AsrMethod,
+		PrayerOffsets:        user.PrayerOffsets,
+		NotificationChannels: user.NotificationChannels,
+		QuietWindows:         user.QuietWindows,
+		Locale:               user.Locale,
 	}
 
 	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: statusCode, Data: respBody})
@@ -0,0 +1,321 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/mdayat/demi-masa/web/repository"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	calendarFeedDays    = 90
+	prayerEventDuration = 15 * time.Minute
+	icsTimeLayout       = "20060102T150405Z"
+)
+
+type icsWriter struct {
+	builder strings.Builder
+}
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// writeLine folds content lines longer than 75 octets as RFC 5545 requires,
+// without splitting a multi-byte character.
+func (w *icsWriter) writeLine(name, value string) {
+	line := name + ":" + value
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && utf8.RuneStart(line[cut]) == false {
+			cut--
+		}
+
+		w.builder.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	w.builder.WriteString(line + "\r\n")
+}
+
+func (w *icsWriter) writeText(name, value string) {
+	w.writeLine(name, icsTextEscaper.Replace(value))
+}
+
+func (w *icsWriter) String() string {
+	return w.builder.String()
+}
+
+// getFeedPrayers returns the prayers of the given number of days starting
+// at from. Days past the months kept in the calendar store are calculated
+// on the spot, they are replaced by the stored ones once the worker renews
+// the calendar. The feed ends early at a day of polar day or night, which
+// has no prayer times to calculate.
+func getFeedPrayers(
+	ctx context.Context,
+	prayerLocation prayer.Location,
+	adjustment prayer.Adjustment,
+	from time.Time,
+	days int,
+) (prayer.PrayerCalendar, error) {
+	prayerCalendar := make(prayer.PrayerCalendar, 0, days)
+	isStored := true
+	for i := 0; i < days; i++ {
+		date := from.AddDate(0, 0, i)
+		if isStored {
+			prayers, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, prayerLocation, adjustment, date)
+			if err == nil {
+				prayerCalendar = append(prayerCalendar, prayers)
+				continue
+			}
+
+			if errors.Is(err, redis.Nil) == false {
+				return nil, errors.Wrap(err, "failed to get prayers for date")
+			}
+			isStored = false
+		}
+
+		prayers, err := prayer.CalculatePrayers(prayerLocation.Latitude, prayerLocation.Longitude, date, prayer.KemenagCalculationParams)
+		if err != nil {
+			if errors.Is(err, prayer.ErrNoSunriseOrSunset) {
+				break
+			}
+			return nil, errors.Wrap(err, "failed to calculate prayers")
+		}
+		prayerCalendar = append(prayerCalendar, prayer.WithSunnahTimes(prayers).Adjust(prayerLocation, adjustment))
+	}
+
+	return prayerCalendar, nil
+}
+
+func getCalendarFeedHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	token := chi.URLParam(req, "token")
+	calendarFeed, err := services.Queries.GetCalendarFeedByTokenHash(ctx, makeSecretTokenHash(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusNotFound).Msg("calendar feed not found")
+			http.Error(res, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		} else {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get calendar feed by token hash")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	prayerLocation, adjustment, err := getUserPrayerLocation(ctx, calendarFeed.UserID)
+	if err != nil {
+		if errors.Is(err, errTimeZoneNotSet) || errors.Is(err, errPrayerCalendarNotReady) {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusConflict).Send()
+			http.Error(res, http.StatusText(http.StatusConflict), http.StatusConflict)
+		} else {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user prayer location")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	location, err := time.LoadLocation(prayerLocation.TimeZone)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to load time zone location")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	now := services.Clock.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	prayerCalendar, err := getFeedPrayers(ctx, prayerLocation, adjustment, today, calendarFeedDays)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get feed prayers")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var tasks []repository.GetTasksByUserIDRow
+	if calendarFeed.IncludeTasks {
+		tasks, err = services.Queries.GetTasksByUserID(ctx, calendarFeed.UserID)
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get tasks by user id")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	dtStamp := start.UTC().Format(icsTimeLayout)
+	var writer icsWriter
+	writer.writeLine("BEGIN", "VCALENDAR")
+	writer.writeLine("VERSION", "2.0")
+	writer.writeLine("PRODID", "-//Demi Masa//Prayer Times//ID")
+	writer.writeLine("CALSCALE", "GREGORIAN")
+	writer.writeLine("METHOD", "PUBLISH")
+	writer.writeText("X-WR-CALNAME", "Demi Masa")
+	writer.writeText("X-WR-TIMEZONE", prayerLocation.TimeZone)
+	writer.writeLine("REFRESH-INTERVAL;VALUE=DURATION", "PT12H")
+	writer.writeLine("X-PUBLISHED-TTL", "PT12H")
+
+	for _, prayers := range prayerCalendar {
+		for _, v := range prayers.Fardhu() {
+			prayerTime := time.Unix(v.UnixTime, 0)
+			writer.writeLine("BEGIN", "VEVENT")
+			writer.writeText("UID", fmt.Sprintf("%s-%s-%s@demi-masa", calendarFeed.UserID, prayerTime.In(location).Format("20060102"), v.Name))
+			writer.writeLine("DTSTAMP", dtStamp)
+			writer.writeLine("DTSTART", prayerTime.UTC().Format(icsTimeLayout))
+			writer.writeLine("DTEND", prayerTime.Add(prayerEventDuration).UTC().Format(icsTimeLayout))
+			writer.writeText("SUMMARY", fmt.Sprintf("Salat %s", v.Name))
+			writer.writeText("LOCATION", prayerLocation.City)
+			writer.writeLine("TRANSP", "TRANSPARENT")
+			writer.writeLine("END", "VEVENT")
+		}
+	}
+
+	for _, v := range tasks {
+		taskID, err := v.ID.Value()
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get task UUID from pgtype.UUID")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		status := "NEEDS-ACTION"
+		if v.Checked {
+			status = "COMPLETED"
+		}
+
+		writer.writeLine("BEGIN", "VTODO")
+		writer.writeText("UID", fmt.Sprintf("%s@demi-masa", taskID))
+		writer.writeLine("DTSTAMP", dtStamp)
+		writer.writeText("SUMMARY", v.Name)
+		if v.Description != "" {
+			writer.writeText("DESCRIPTION", v.Description)
+		}
+		writer.writeLine("STATUS", status)
+		writer.writeLine("END", "VTODO")
+	}
+	writer.writeLine("END", "VCALENDAR")
+
+	res.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	_, err = res.Write([]byte(writer.String()))
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to write calendar feed")
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func createCalendarFeedHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		IncludeTasks bool `json:"include_tasks"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	token, err := generateSecretToken()
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to generate calendar token")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpsertCalendarFeed(ctx, repository.UpsertCalendarFeedParams{
+		UserID:       userID,
+		TokenHash:    makeSecretTokenHash(token),
+		IncludeTasks: body.IncludeTasks,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to upsert calendar feed")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respBody := struct {
+		Token        string `json:"token"`
+		Path         string `json:"path"`
+		IncludeTasks bool   `json:"include_tasks"`
+	}{
+		Token:        token,
+		Path:         fmt.Sprintf("/calendar/%s.ics", token),
+		IncludeTasks: body.IncludeTasks,
+	}
+
+	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusCreated, Data: respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusCreated).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updateCalendarFeedHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		IncludeTasks bool `json:"include_tasks"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	affectedRows, err := services.Queries.UpdateCalendarFeedIncludeTasks(ctx, repository.UpdateCalendarFeedIncludeTasksParams{
+		UserID:       userID,
+		IncludeTasks: body.IncludeTasks,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update calendar feed")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if affectedRows == 0 {
+		logWithCtx.Error().Caller().Int("status_code", http.StatusNotFound).Msg("calendar feed not found")
+		http.Error(res, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func deleteCalendarFeedHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err := services.Queries.DeleteCalendarFeed(ctx, userID)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to delete calendar feed")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
@@ -0,0 +1,277 @@
+package internal
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/web/configs/env"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/mdayat/demi-masa/web/repository"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/twilio/twilio-go/client"
+)
+
+// checkInReplies are the replies, in every supported locale, that mark the
+// current prayer as done.
+var checkInReplies = []string{
+	"sudah", "udah", "sdh", "selesai",
+	"done", "prayed",
+	"wis", "uwis", "sampun",
+	"atos", "parantos",
+	"✅", "✔", "✔️", "👍",
+}
+
+func isCheckInReply(body string) bool {
+	reply := strings.ToLower(strings.Trim(strings.TrimSpace(body), ".!"))
+	return slices.Contains(checkInReplies, reply)
+}
+
+var prayerCheckInKinds = map[repository.PrayerStatus]message.Kind{
+	repository.PrayerStatusONTIME: message.PrayerCheckInOnTimeKind,
+	repository.PrayerStatusLATE:   message.PrayerCheckInLateKind,
+	repository.PrayerStatusMISSED: message.PrayerCheckInMissedKind,
+}
+
+// getCurrentPrayer returns the latest fardhu prayer that has started by
+// now, which is yesterday's Isya before today's Subuh.
+func getCurrentPrayer(
+	ctx context.Context,
+	prayerLocation prayer.Location,
+	adjustment prayer.Adjustment,
+	now time.Time,
+) (prayer.Prayer, error) {
+	todayPrayers, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, prayerLocation, adjustment, now)
+	if err != nil {
+		return prayer.Prayer{}, errors.Wrap(err, "failed to get today prayers")
+	}
+
+	fardhuPrayers := todayPrayers.Fardhu()
+	for i := len(fardhuPrayers) - 1; i >= 0; i-- {
+		if fardhuPrayers[i].UnixTime <= now.Unix() {
+			return fardhuPrayers[i], nil
+		}
+	}
+
+	yesterdayPrayers, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, prayerLocation, adjustment, now.AddDate(0, 0, -1))
+	if err != nil {
+		return prayer.Prayer{}, errors.Wrap(err, "failed to get yesterday prayers")
+	}
+
+	isya, ok := yesterdayPrayers.Get(prayer.IsyaPrayerName)
+	if !ok {
+		return prayer.Prayer{}, errors.New("yesterday prayers have no isya")
+	}
+	return isya, nil
+}
+
+// createUserPrayer stores the user's prayers of the day, the way
+// getTodayPrayersHandler does for users who never opened the app since, and
+// returns the id of the named one.
+func createUserPrayer(
+	ctx context.Context,
+	userID string,
+	prayerLocation prayer.Location,
+	adjustment prayer.Adjustment,
+	location *time.Location,
+	prayerName string,
+) (pgtype.UUID, error) {
+	usedPrayers, err := getUsedPrayers(ctx, prayerLocation, adjustment, location)
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+
+	subuhPrayer, _ := usedPrayers.Get(prayer.SubuhPrayerName)
+	subuhTime := time.Unix(subuhPrayer.UnixTime, 0).In(location)
+	params := bulkInsertPrayerParams{
+		userID: userID,
+		year:   int16(subuhTime.Year()),
+		month:  int16(subuhTime.Month()),
+		day:    int16(subuhTime.Day()),
+	}
+
+	todayPrayers, err := services.Queries.GetTodayPrayers(ctx, repository.GetTodayPrayersParams{
+		UserID: params.userID,
+		Year:   params.year,
+		Month:  params.month,
+		Day:    params.day,
+	})
+
+	if err != nil {
+		return pgtype.UUID{}, errors.Wrap(err, "failed to get today prayers")
+	}
+
+	if len(todayPrayers) == 0 {
+		todayPrayers, err = bulkInsertPrayer(ctx, usedPrayers, &params)
+		if err != nil {
+			return pgtype.UUID{}, err
+		}
+	}
+
+	for _, v := range todayPrayers {
+		if v.Name == prayerName {
+			return v.ID, nil
+		}
+	}
+
+	return pgtype.UUID{}, errors.Wrap(pgx.ErrNoRows, fmt.Sprintf("user has no %s prayer today", prayerName))
+}
+
+// checkInCurrentPrayer marks the user's current prayer with the same rules
+// as updatePrayerHandler and returns the kind of message to reply with. A
+// user who has not opened the app today gets the day's prayers stored first.
+func checkInCurrentPrayer(ctx context.Context, user repository.User) (message.Kind, string, error) {
+	prayerLocation, adjustment, err := getUserPrayerLocation(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, errTimeZoneNotSet) || errors.Is(err, errPrayerCalendarNotReady) {
+			return message.PrayerCheckInUnavailableKind, "", nil
+		}
+		return "", "", err
+	}
+
+	location, err := time.LoadLocation(prayerLocation.TimeZone)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to load time zone location")
+	}
+
+	now := services.Clock.Now().In(location)
+	currentPrayer, err := getCurrentPrayer(ctx, prayerLocation, adjustment, now)
+	if err != nil {
+		return "", "", err
+	}
+
+	prayerTime := time.Unix(currentPrayer.UnixTime, 0).In(location)
+	userPrayer, err := services.Queries.GetPrayerByUserIDAndDate(ctx, repository.GetPrayerByUserIDAndDateParams{
+		UserID: user.ID,
+		Name:   currentPrayer.Name,
+		Year:   int16(prayerTime.Year()),
+		Month:  int16(prayerTime.Month()),
+		Day:    int16(prayerTime.Day()),
+	})
+
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		userPrayer.ID, err = createUserPrayer(ctx, user.ID, prayerLocation, adjustment, location, currentPrayer.Name)
+		if err != nil && errors.Is(err, pgx.ErrNoRows) {
+			return message.PrayerCheckInUnavailableKind, currentPrayer.Name, nil
+		}
+
+		if err != nil {
+			return "", "", errors.Wrap(err, "failed to create user prayer")
+		}
+	}
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to get prayer by user id and date")
+	}
+
+	if userPrayer.Status.Valid {
+		return message.PrayerCheckInDuplicateKind, currentPrayer.Name, nil
+	}
+
+	prayerStatus, err := getPrayerStatus(ctx, prayerLocation, adjustment, currentPrayer, now)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = services.Queries.UpdatePrayerStatus(ctx, repository.UpdatePrayerStatusParams{
+		ID:     userPrayer.ID,
+		Status: repository.NullPrayerStatus{PrayerStatus: prayerStatus, Valid: true},
+	})
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to update prayer status")
+	}
+	return prayerCheckInKinds[prayerStatus], currentPrayer.Name, nil
+}
+
+type twiMLMessage struct {
+	XMLName xml.Name `xml:"Response"`
+	Message string   `xml:"Message,omitempty"`
+}
+
+// sendTwiMLResponse answers a twilio webhook, an empty text sends no reply.
+func sendTwiMLResponse(res http.ResponseWriter, text string) error {
+	body, err := xml.Marshal(twiMLMessage{Message: text})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal twiml response")
+	}
+
+	res.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	res.WriteHeader(http.StatusOK)
+	_, err = res.Write(append([]byte(xml.Header), body...))
+	return err
+}
+
+// twilioInboundWebhookHandler lets users check in the current prayer by
+// replying to a reminder on whatsapp. Messages from unknown numbers are
+// dropped without a reply.
+func twilioInboundWebhookHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	err := req.ParseForm()
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("failed to parse twilio inbound message form")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	params := make(map[string]string, len(req.PostForm))
+	for key := range req.PostForm {
+		params[key] = req.PostForm.Get(key)
+	}
+
+	validator := client.NewRequestValidator(env.TWILIO_AUTH_TOKEN)
+	if validator.Validate(env.TWILIO_INBOUND_URL, params, req.Header.Get("X-Twilio-Signature")) == false {
+		logWithCtx.Error().Caller().Int("status_code", http.StatusForbidden).Msg("invalid signature")
+		http.Error(res, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	phoneNumber := strings.TrimPrefix(params["From"], "whatsapp:")
+	user, err := services.Queries.GetUserByPhoneNumber(ctx, pgtype.Text{String: phoneNumber, Valid: true})
+	if err != nil && errors.Is(err, pgx.ErrNoRows) == false {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user by phone number")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var replyText string
+	if err == nil && user.PhoneVerified {
+		messageKind := message.PrayerCheckInHelpKind
+		var prayerName string
+		if isCheckInReply(params["Body"]) {
+			messageKind, prayerName, err = checkInCurrentPrayer(ctx, user)
+			if err != nil {
+				logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Str("user_id", user.ID).Msg("failed to check in current prayer")
+				http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		reply, err := services.MessageStore.Render(ctx, messageKind, message.ParseLocale(user.Locale), message.Data{PrayerName: prayerName})
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to render check in reply")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		replyText = reply.Body
+	}
+
+	err = sendTwiMLResponse(res, replyText)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send twiml response")
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/mdayat/demi-masa/web/repository"
+	"github.com/rs/zerolog/log"
+)
+
+func registerDeviceHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		Token string `json:"token" validate:"required,max=512"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	// A token moves to the signed in user when a device switches accounts.
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpsertUserDevice(ctx, repository.UpsertUserDeviceParams{
+		UserID: userID,
+		Token:  body.Token,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to upsert user device")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusCreated)
+	logWithCtx.Info().Int("status_code", http.StatusCreated).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func unregisterDeviceHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	affectedRows, err := services.Queries.DeleteUserDevice(ctx, repository.DeleteUserDeviceParams{
+		UserID: userID,
+		Token:  chi.URLParam(req, "token"),
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to delete user device")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if affectedRows == 0 {
+		logWithCtx.Error().Caller().Int("status_code", http.StatusNotFound).Msg("user device not found")
+		http.Error(res, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
@@ -2,10 +2,14 @@ package internal
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/mdayat/demi-masa/web/configs/services"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -33,13 +37,103 @@ func authenticate(next http.Handler) http.Handler {
 	})
 }
 
+func makeAPIKeyQuotaKey(apiKeyID string, day time.Time) string {
+	return fmt.Sprintf("api_key:quota:%s:%s", apiKeyID, day.Format("20060102"))
+}
+
+// authenticateAPIKey guards the public routes. Each key has a daily quota
+// counted in redis, the count resets at midnight UTC.
+func authenticateAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		logWithCtx := log.Ctx(ctx).With().Logger()
+		key := req.Header.Get("X-API-Key")
+		if key == "" {
+			err := errors.New("missing api key header")
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusUnauthorized).Send()
+			http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		apiKey, err := services.Queries.GetAPIKeyByHash(ctx, makeSecretTokenHash(key))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusUnauthorized).Msg("invalid api key")
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			} else {
+				logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get api key by hash")
+				http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		apiKeyID, err := apiKey.ID.Value()
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get api key UUID from pgtype.UUID")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		now := services.Clock.Now().UTC()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		resetAt := today.AddDate(0, 0, 1)
+		quotaKey := makeAPIKeyQuotaKey(fmt.Sprintf("%s", apiKeyID), today)
+
+		count, err := services.RedisClient.Incr(ctx, quotaKey).Result()
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to increment api key quota")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if count == 1 {
+			err = services.RedisClient.ExpireAt(ctx, quotaKey, resetAt.Add(24*time.Hour)).Err()
+			if err != nil {
+				logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to set api key quota expiration")
+				http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		remaining := int64(apiKey.DailyQuota) - count
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		res.Header().Set("X-RateLimit-Limit", strconv.Itoa(int(apiKey.DailyQuota)))
+		res.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+		res.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+		if count > int64(apiKey.DailyQuota) {
+			err := errors.New("api key daily quota exceeded")
+			res.Header().Set("Retry-After", strconv.Itoa(int(resetAt.Sub(now).Seconds())))
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusTooManyRequests).Send()
+			http.Error(res, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(res, req)
+	})
+}
+
+const calendarFeedPathPrefix = "/calendar/"
+
+// getLoggedPath redacts the subscription token of calendar feed paths, it
+// is the only secret the feed needs.
+func getLoggedPath(path string) string {
+	if strings.HasPrefix(path, calendarFeedPathPrefix) {
+		return calendarFeedPathPrefix + "{token}.ics"
+	}
+	return path
+}
+
 func logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		subLogger := log.
 			With().
 			Str("request_id", uuid.New().String()).
 			Str("method", req.Method).
-			Str("path", req.URL.Path).
+			Str("path", getLoggedPath(req.URL.Path)).
 			Str("client_ip", req.RemoteAddr).
 			Logger()
 
@@ -0,0 +1,21 @@
+package internal
+
+import "testing"
+
+func TestGetLoggedPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/calendar/0123456789abcdef.ics", want: "/calendar/{token}.ics"},
+		{path: "/prayers/today", want: "/prayers/today"},
+		{path: "/public/v1/prayer-times", want: "/public/v1/prayer-times"},
+	}
+
+	for _, test := range tests {
+		got := getLoggedPath(test.path)
+		if got != test.want {
+			t.Errorf("getLoggedPath(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
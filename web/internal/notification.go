@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/pkg/notifier"
+	"github.com/mdayat/demi-masa/web/configs/env"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/mdayat/demi-masa/web/repository"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/twilio/twilio-go/client"
+)
+
+const (
+	defaultNotificationsLimit = 20
+	maxNotificationsLimit     = 100
+)
+
+func createNotification(ctx context.Context, userID string, kind message.Kind, delivery notifier.Delivery, sendErr error) error {
+	params := repository.CreateNotificationParams{
+		UserID:      userID,
+		Kind:        string(kind),
+		Channel:     pgtype.Text{String: string(delivery.Channel), Valid: delivery.Channel != ""},
+		ProviderSid: pgtype.Text{String: delivery.ProviderID, Valid: delivery.ProviderID != ""},
+		Status:      string(delivery.Status),
+	}
+
+	if sendErr != nil {
+		params.Status = string(notifier.FailedStatus)
+		params.Error = pgtype.Text{String: sendErr.Error(), Valid: true}
+	}
+
+	return services.Queries.CreateNotification(ctx, params)
+}
+
+type notificationRespBody struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Channel   string    `json:"channel,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func getNotificationsHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	before := services.Clock.Now()
+	if value := req.URL.Query().Get("before"); value != "" {
+		unixTime, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid before query param")
+			http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		before = time.Unix(unixTime, 0)
+	}
+
+	limit := defaultNotificationsLimit
+	if value := req.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxNotificationsLimit {
+			err = errors.New(fmt.Sprintf("invalid limit query param: %s", value))
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Send()
+			http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	notifications, err := services.Queries.GetNotificationsByUserID(ctx, repository.GetNotificationsByUserIDParams{
+		UserID:    userID,
+		CreatedAt: pgtype.Timestamptz{Time: before, Valid: true},
+		Limit:     int32(limit),
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get notifications by user id")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respBody := make([]notificationRespBody, len(notifications))
+	for i, v := range notifications {
+		notificationID, err := v.ID.Value()
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get notification UUID from pgtype.UUID")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		respBody[i] = notificationRespBody{
+			ID:        fmt.Sprintf("%s", notificationID),
+			Kind:      v.Kind,
+			Channel:   v.Channel.String,
+			Status:    v.Status,
+			Error:     v.Error.String,
+			CreatedAt: v.CreatedAt.Time,
+			UpdatedAt: v.UpdatedAt.Time,
+		}
+	}
+
+	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusOK, Data: &respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+// twilioStatusWebhookHandler receives the delivery state twilio reports to
+// the status callback of every message. The signature is computed over the
+// configured callback URL, since the request URL differs behind a proxy.
+func twilioStatusWebhookHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	err := req.ParseForm()
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("failed to parse twilio status callback form")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	params := make(map[string]string, len(req.PostForm))
+	for key := range req.PostForm {
+		params[key] = req.PostForm.Get(key)
+	}
+
+	validator := client.NewRequestValidator(env.TWILIO_AUTH_TOKEN)
+	if validator.Validate(env.TWILIO_STATUS_CALLBACK_URL, params, req.Header.Get("X-Twilio-Signature")) == false {
+		logWithCtx.Error().Caller().Int("status_code", http.StatusForbidden).Msg("invalid signature")
+		http.Error(res, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	messageSID := params["MessageSid"]
+	status, ok := notifier.ParseTwilioStatus(params["MessageStatus"])
+	if messageSID == "" || ok == false {
+		logWithCtx.Warn().Str("message_sid", messageSID).Str("message_status", params["MessageStatus"]).Msg("twilio status ignored")
+		logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+		return
+	}
+
+	fromStatuses := make([]string, 0, len(status.PrecedingStatuses()))
+	for _, v := range status.PrecedingStatuses() {
+		fromStatuses = append(fromStatuses, string(v))
+	}
+
+	errorCode := params["ErrorCode"]
+	affectedRows, err := services.Queries.UpdateNotificationStatus(ctx, repository.UpdateNotificationStatusParams{
+		Status:       string(status),
+		Error:        pgtype.Text{String: errorCode, Valid: errorCode != ""},
+		ProviderSid:  pgtype.Text{String: messageSID, Valid: true},
+		FromStatuses: fromStatuses,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Str("message_sid", messageSID).Msg("failed to update notification status")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// Zero rows means an unknown message or a callback older than the
+	// recorded status, neither is worth a twilio retry.
+	if affectedRows == 0 {
+		logWithCtx.Warn().Str("message_sid", messageSID).Str("status", string(status)).Msg("notification status not updated")
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
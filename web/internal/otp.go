@@ -8,12 +8,13 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/pkg/notifier"
 	"github.com/mdayat/demi-masa/web/configs/services"
 	"github.com/mdayat/demi-masa/web/repository"
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
-	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
 const (
@@ -155,14 +156,36 @@ func generateOTPHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	params := twilioApi.CreateMessageParams{}
-	params.SetFrom("whatsapp:+14155238886")
-	params.SetTo(fmt.Sprintf("whatsapp:%s", body.PhoneNumber))
-	params.SetBody(fmt.Sprintf("Berikut adalah kode OTP Anda: %s", otp))
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	locale, err := services.Queries.GetUserLocaleByID(ctx, userID)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user locale by id")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
 
-	_, err = services.TwilioClient.Api.CreateMessage(&params)
+	otpMessage, err := services.MessageStore.Render(ctx, message.OTPKind, message.ParseLocale(locale), message.Data{Code: otp})
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send otp")
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to render otp message")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// The otp verifies the phone number, so it never falls back to email.
+	delivery, sendErr := services.Notifier.Send(
+		ctx,
+		[]notifier.Channel{notifier.WhatsAppChannel, notifier.SMSChannel},
+		notifier.Recipient{PhoneNumber: body.PhoneNumber},
+		otpMessage,
+	)
+
+	err = createNotification(ctx, userID, message.OTPKind, delivery, sendErr)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Str("user_id", userID).Msg("failed to create notification")
+	}
+
+	if sendErr != nil {
+		logWithCtx.Error().Err(sendErr).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send otp")
 		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/mdayat/demi-masa/web/repository"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const maxReminderPause = 90 * 24 * time.Hour
+
+type reminderPauseRespBody struct {
+	PausedUntil int64 `json:"paused_until,omitempty"`
+	Active      bool  `json:"active"`
+}
+
+func getReminderPauseHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	pausedUntil, err := services.Queries.GetUserRemindersPausedUntilByID(ctx, userID)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user reminders paused until by id")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// An expired pause is reported as no pause at all.
+	respBody := reminderPauseRespBody{}
+	if pausedUntil.Valid && pausedUntil.Time.After(services.Clock.Now()) {
+		respBody.PausedUntil = pausedUntil.Time.Unix()
+		respBody.Active = true
+	}
+
+	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusOK, Data: &respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updateReminderPauseHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		Until int64 `json:"until" validate:"required"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	now := services.Clock.Now()
+	until := time.Unix(body.Until, 0)
+	if until.After(now) == false || until.Sub(now) > maxReminderPause {
+		err := errors.New(fmt.Sprintf("reminder pause must end within %s from now", maxReminderPause))
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Send()
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpdateUserRemindersPausedUntil(ctx, repository.UpdateUserRemindersPausedUntilParams{
+		ID:                   userID,
+		RemindersPausedUntil: pgtype.Timestamptz{Time: until, Valid: true},
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update user reminders paused until")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respBody := reminderPauseRespBody{PausedUntil: until.Unix(), Active: true}
+	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusOK, Data: &respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func deleteReminderPauseHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err := services.Queries.UpdateUserRemindersPausedUntil(ctx, repository.UpdateUserRemindersPausedUntilParams{
+		ID:                   userID,
+		RemindersPausedUntil: pgtype.Timestamptz{},
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to clear user reminders paused until")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
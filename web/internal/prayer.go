@@ -10,21 +10,21 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
-	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/mdayat/demi-masa/pkg/prayer"
-	"github.com/mdayat/demi-masa/pkg/task"
 	"github.com/mdayat/demi-masa/web/configs/services"
 	"github.com/mdayat/demi-masa/web/repository"
 	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
 type prayerRespBody struct {
-	ID       string                  `json:"id"`
-	Name     string                  `json:"name"`
-	Status   repository.PrayerStatus `json:"status,omitempty"`
-	UnixTime int64                   `json:"unix_time,omitempty"`
+	ID        string                  `json:"id"`
+	Name      string                  `json:"name"`
+	Status    repository.PrayerStatus `json:"status,omitempty"`
+	UnixTime  int64                   `json:"unix_time,omitempty"`
+	HijriDate prayer.HijriDate        `json:"hijri_date"`
 }
 
 func getPrayersHandler(res http.ResponseWriter, req *http.Request) {
@@ -80,9 +80,10 @@ func getPrayersHandler(res http.ResponseWriter, req *http.Request) {
 		}
 
 		respBody[i] = prayerRespBody{
-			ID:     fmt.Sprintf("%s", prayerID),
-			Name:   v.Name,
-			Status: v.Status.PrayerStatus,
+			ID:        fmt.Sprintf("%s", prayerID),
+			Name:      v.Name,
+			Status:    v.Status.PrayerStatus,
+			HijriDate: services.HijriCalendar.FromGregorian(time.Date(year, time.Month(month), int(v.Day), 0, 0, 0, 0, time.UTC)),
 		}
 	}
 
@@ -95,44 +96,84 @@ func getPrayersHandler(res http.ResponseWriter, req *http.Request) {
 	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
 }
 
+var (
+	errTimeZoneNotSet         = errors.New("user time zone is not set")
+	errPrayerCalendarNotReady = errors.New("prayer calendar of user location is not ready")
+)
+
+// getUserPrayerLocation falls back to the time zone location until the
+// worker has stored the calendar of the user's own location. Time zones
+// without a fallback location have to wait for the worker.
+func getUserPrayerLocation(ctx context.Context, userID string) (prayer.Location, prayer.Adjustment, error) {
+	userLocation, err := services.Queries.GetUserLocationByID(ctx, userID)
+	if err != nil {
+		return prayer.Location{}, prayer.Adjustment{}, errors.Wrap(err, "failed to get user location by id")
+	}
+
+	if userLocation.TimeZone.Valid == false {
+		return prayer.Location{}, prayer.Adjustment{}, errTimeZoneNotSet
+	}
+
+	adjustment, err := prayer.NewAdjustment(string(userLocation.AsrMethod), userLocation.PrayerOffsets)
+	if err != nil {
+		return prayer.Location{}, prayer.Adjustment{}, errors.Wrap(err, "failed to create prayer adjustment")
+	}
+
+	timeZone := userLocation.TimeZone.String
+	if userLocation.Latitude.Valid && userLocation.Longitude.Valid {
+		prayerLocation := prayer.NewLocation(
+			userLocation.City.String,
+			userLocation.Latitude.Float64,
+			userLocation.Longitude.Float64,
+			timeZone,
+		)
+
+		_, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, prayerLocation, adjustment, services.Clock.Now())
+		if err == nil {
+			return prayerLocation, adjustment, nil
+		}
+
+		if errors.Is(err, redis.Nil) == false {
+			return prayer.Location{}, prayer.Adjustment{}, errors.Wrap(err, "failed to get today prayers")
+		}
+	}
+
+	prayerLocation, err := prayer.GetTimeZoneLocation(timeZone)
+	if err != nil && errors.Is(err, prayer.ErrNoTimeZoneLocation) {
+		return prayer.Location{}, prayer.Adjustment{}, errPrayerCalendarNotReady
+	}
+
+	if err != nil {
+		return prayer.Location{}, prayer.Adjustment{}, err
+	}
+
+	return prayerLocation, adjustment, nil
+}
+
+// getUsedPrayers returns yesterday's prayers until today's subuh comes.
 func getUsedPrayers(
 	ctx context.Context,
+	prayerLocation prayer.Location,
+	adjustment prayer.Adjustment,
 	location *time.Location,
 ) (prayer.Prayers, error) {
-	timeZone := repository.IndonesiaTimeZone(location.String())
-	prayerCalendar, err := prayer.GetPrayerCalendar(ctx, services.RedisClient, string(timeZone))
+	now := services.Clock.Now().In(location)
+	todayPrayers, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, prayerLocation, adjustment, now)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get prayer calendar")
+		return nil, errors.Wrap(err, "failed to get today prayers")
 	}
 
-	now := time.Now().In(location)
-	currentDay := now.Day()
-	currentUnixTime := now.Unix()
-	isLastDay := prayer.IsLastDay(now)
-
-	todayPrayer := prayerCalendar[currentDay-1]
-	subuhPrayer := todayPrayer[0]
-	var usedPrayers prayer.Prayers
+	subuhPrayer, _ := todayPrayers.Get(prayer.SubuhPrayerName)
+	if now.Unix() >= subuhPrayer.UnixTime {
+		return todayPrayers, nil
+	}
 
-	if currentDay == 1 && currentUnixTime < subuhPrayer.UnixTime ||
-		isLastDay && currentUnixTime > subuhPrayer.UnixTime {
-		usedPrayers, err = prayer.GetLastDayPrayer(ctx, services.RedisClient, string(timeZone))
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to get last day prayer")
-		}
-	} else if isLastDay && currentUnixTime < subuhPrayer.UnixTime {
-		usedPrayers, err = prayer.GetPenultimateDayPrayer(ctx, services.RedisClient, string(timeZone))
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to get penultimate day prayer")
-		}
-	} else if isLastDay == false && currentUnixTime < subuhPrayer.UnixTime {
-		yesterdayPrayer := prayerCalendar[currentDay-2]
-		usedPrayers = yesterdayPrayer
-	} else {
-		usedPrayers = todayPrayer
+	yesterdayPrayers, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, prayerLocation, adjustment, now.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get yesterday prayers")
 	}
 
-	return usedPrayers, nil
+	return yesterdayPrayers, nil
 }
 
 type bulkInsertPrayerParams struct {
@@ -147,14 +188,11 @@ func bulkInsertPrayer(
 	usedPrayers prayer.Prayers,
 	arg *bulkInsertPrayerParams,
 ) ([]repository.GetTodayPrayersRow, error) {
-	createPrayersParams := make([]repository.CreatePrayersParams, 0, len(usedPrayers)-1)
-	todayPrayers := make([]repository.GetTodayPrayersRow, 0, len(usedPrayers)-1)
-
-	for _, v := range usedPrayers {
-		if v.Name == prayer.SunriseTimeName {
-			continue
-		}
+	fardhuPrayers := usedPrayers.Fardhu()
+	createPrayersParams := make([]repository.CreatePrayersParams, 0, len(fardhuPrayers))
+	todayPrayers := make([]repository.GetTodayPrayersRow, 0, len(fardhuPrayers))
 
+	for _, v := range fardhuPrayers {
 		prayerUUID := uuid.New()
 		createPrayersParams = append(createPrayersParams, repository.CreatePrayersParams{
 			ID:     pgtype.UUID{Bytes: prayerUUID, Valid: true},
@@ -183,28 +221,40 @@ func getTodayPrayersHandler(res http.ResponseWriter, req *http.Request) {
 	start := time.Now()
 	ctx := req.Context()
 	logWithCtx := log.Ctx(ctx).With().Logger()
-	userTimeZone := req.URL.Query().Get("time_zone")
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
 
-	location, err := time.LoadLocation(userTimeZone)
+	prayerLocation, adjustment, err := getUserPrayerLocation(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errTimeZoneNotSet) || errors.Is(err, errPrayerCalendarNotReady) {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusConflict).Send()
+			http.Error(res, http.StatusText(http.StatusConflict), http.StatusConflict)
+		} else {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user prayer location")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	location, err := time.LoadLocation(prayerLocation.TimeZone)
 	if err != nil {
 		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to load time zone location")
 		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	usedPrayers, err := getUsedPrayers(ctx, location)
+	usedPrayers, err := getUsedPrayers(ctx, prayerLocation, adjustment, location)
 	if err != nil {
 		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get used prayers")
 		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	subuhTime := time.Unix(usedPrayers[0].UnixTime, 0).In(location)
+	subuhPrayer, _ := usedPrayers.Get(prayer.SubuhPrayerName)
+	subuhTime := time.Unix(subuhPrayer.UnixTime, 0).In(location)
 	usedPrayersYear := subuhTime.Year()
 	usedPrayersMonth := subuhTime.Month()
 	usedPrayersDay := subuhTime.Day()
 
-	userID := fmt.Sprintf("%s", ctx.Value("userID"))
 	todayPrayers, err := services.Queries.GetTodayPrayers(ctx, repository.GetTodayPrayersParams{
 		UserID: userID,
 		Year:   int16(usedPrayersYear),
@@ -233,12 +283,9 @@ func getTodayPrayersHandler(res http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	hijriDate := services.HijriCalendar.FromGregorian(subuhTime)
 	respBody := make([]prayerRespBody, 0, len(todayPrayers))
-	for _, v := range usedPrayers {
-		if v.Name == prayer.SunriseTimeName {
-			continue
-		}
-
+	for _, v := range usedPrayers.Fardhu() {
 		for _, p := range todayPrayers {
 			if p.Name != v.Name {
 				continue
@@ -252,10 +299,11 @@ func getTodayPrayersHandler(res http.ResponseWriter, req *http.Request) {
 			}
 
 			respBody = append(respBody, prayerRespBody{
-				ID:       fmt.Sprintf("%s", prayerID),
-				Name:     p.Name,
-				Status:   p.Status.PrayerStatus,
-				UnixTime: v.UnixTime,
+				ID:        fmt.Sprintf("%s", prayerID),
+				Name:      p.Name,
+				Status:    p.Status.PrayerStatus,
+				UnixTime:  v.UnixTime,
+				HijriDate: hijriDate,
 			})
 			break
 		}
@@ -270,155 +318,182 @@ func getTodayPrayersHandler(res http.ResponseWriter, req *http.Request) {
 	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
 }
 
-func updatePrayerHandler(res http.ResponseWriter, req *http.Request) {
+type prayerTimeRespBody struct {
+	Name     string `json:"name"`
+	UnixTime int64  `json:"unix_time"`
+}
+
+type makruhWindowRespBody struct {
+	Name          string `json:"name"`
+	StartUnixTime int64  `json:"start_unix_time"`
+	EndUnixTime   int64  `json:"end_unix_time"`
+}
+
+type prayerTimesRespBody struct {
+	HijriDate     prayer.HijriDate       `json:"hijri_date"`
+	Times         []prayerTimeRespBody   `json:"times"`
+	MakruhWindows []makruhWindowRespBody `json:"makruh_windows"`
+}
+
+func getTodayPrayerTimesHandler(res http.ResponseWriter, req *http.Request) {
 	start := time.Now()
 	ctx := req.Context()
 	logWithCtx := log.Ctx(ctx).With().Logger()
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
 
-	var body struct {
-		PrayerName     string                       `json:"prayer_name" validate:"required"`
-		PrayerUnixTime int64                        `json:"prayer_unix_time" validate:"required"`
-		TimeZone       repository.IndonesiaTimeZone `json:"time_zone" validate:"required"`
-		CheckedAt      int64                        `json:"checked_at" validate:"required"`
-		AccountType    repository.AccountType       `json:"account_type" validate:"required"`
-	}
-
-	err := decodeAndValidateJSONBody(req, &body)
+	prayerLocation, adjustment, err := getUserPrayerLocation(ctx, userID)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
-		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		if errors.Is(err, errTimeZoneNotSet) || errors.Is(err, errPrayerCalendarNotReady) {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusConflict).Send()
+			http.Error(res, http.StatusText(http.StatusConflict), http.StatusConflict)
+		} else {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user prayer location")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
 		return
 	}
 
-	prayerCalendar, err := prayer.GetPrayerCalendar(ctx, services.RedisClient, string(body.TimeZone))
+	location, err := time.LoadLocation(prayerLocation.TimeZone)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get prayer calendar")
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to load time zone location")
 		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	location, err := time.LoadLocation(string(body.TimeZone))
+	usedPrayers, err := getUsedPrayers(ctx, prayerLocation, adjustment, location)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to load time zone location")
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get used prayers")
 		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	prayerTime := time.Unix(body.PrayerUnixTime, 0).In(location)
-	prayerDay := prayerTime.Day()
-	isLastDayPrayer := prayer.IsLastDay(prayerTime)
-	isPenultimateDayPrayer := prayer.IsPenultimateDay(prayerTime)
+	subuhPrayer, _ := usedPrayers.Get(prayer.SubuhPrayerName)
+	respBody := prayerTimesRespBody{
+		HijriDate:     services.HijriCalendar.FromGregorian(time.Unix(subuhPrayer.UnixTime, 0).In(location)),
+		Times:         make([]prayerTimeRespBody, 0, len(usedPrayers)),
+		MakruhWindows: make([]makruhWindowRespBody, 0),
+	}
+
+	for _, v := range usedPrayers {
+		respBody.Times = append(respBody.Times, prayerTimeRespBody{Name: v.Name, UnixTime: v.UnixTime})
+	}
+
+	for _, v := range usedPrayers.MakruhWindows() {
+		respBody.MakruhWindows = append(respBody.MakruhWindows, makruhWindowRespBody{
+			Name:          v.Name,
+			StartUnixTime: v.Start,
+			EndUnixTime:   v.End,
+		})
+	}
 
-	checkedTime := time.Unix(body.CheckedAt, 0).In(location)
-	isCheckedAtLastDay := prayer.IsLastDay(checkedTime)
+	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusOK, Data: &respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
 
-	var usedPrayers prayer.Prayers
-	if isPenultimateDayPrayer && isCheckedAtLastDay && body.PrayerName != prayer.IsyaPrayerName {
-		usedPrayers, err = prayer.GetPenultimateDayPrayer(ctx, services.RedisClient, string(body.TimeZone))
-		if err != nil {
-			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get penultimate day prayer")
-			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
-	} else if isPenultimateDayPrayer && isCheckedAtLastDay && body.PrayerName == prayer.IsyaPrayerName ||
-		isLastDayPrayer && body.PrayerName != prayer.IsyaPrayerName {
-		usedPrayers, err = prayer.GetLastDayPrayer(ctx, services.RedisClient, string(body.TimeZone))
-		if err != nil {
-			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get last day prayer")
-			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
-	} else if isLastDayPrayer && body.PrayerName == prayer.IsyaPrayerName {
-		prayerDay = 1
+// getPrayerStatus rates a prayer checked at checkedAt: on time within the
+// first three quarters of its window, late in the last quarter and missed
+// once the next prayer, or sunrise for Subuh, has come.
+func getPrayerStatus(
+	ctx context.Context,
+	prayerLocation prayer.Location,
+	adjustment prayer.Adjustment,
+	checkedPrayer prayer.Prayer,
+	checkedAt time.Time,
+) (repository.PrayerStatus, error) {
+	location, err := time.LoadLocation(prayerLocation.TimeZone)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load time zone location")
 	}
 
+	prayerTime := time.Unix(checkedPrayer.UnixTime, 0).In(location)
 	var nextPrayer prayer.Prayer
-	if body.PrayerName == prayer.SubuhPrayerName {
-		var sunriseTime prayer.Prayer
-		if usedPrayers != nil {
-			sunriseTime = usedPrayers[1]
-		} else {
-			sunriseTime = prayerCalendar[prayerDay-1][1]
+	if checkedPrayer.Name == prayer.SubuhPrayerName {
+		usedPrayers, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, prayerLocation, adjustment, prayerTime)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get prayers for date")
 		}
-		nextPrayer = sunriseTime
+		nextPrayer, _ = usedPrayers.Get(prayer.SunriseTimeName)
 	} else {
-		nextPrayer = prayer.GetNextPrayer(prayerCalendar, usedPrayers, prayerDay, body.PrayerUnixTime)
+		nextPrayer, err = services.PrayerCalendarStore.NextPrayerAfter(ctx, prayerLocation, adjustment, prayerTime)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get next prayer")
+		}
 	}
 
-	prayersDistance := nextPrayer.UnixTime - body.PrayerUnixTime
+	prayersDistance := nextPrayer.UnixTime - checkedPrayer.UnixTime
 	distanceQuarter := int(math.Round(float64(prayersDistance) * 0.25))
-	distanceToNextPrayer := nextPrayer.UnixTime - body.CheckedAt
+	distanceToNextPrayer := nextPrayer.UnixTime - checkedAt.Unix()
 
-	var prayerStatus repository.PrayerStatus
-	if body.CheckedAt > nextPrayer.UnixTime {
-		prayerStatus = repository.PrayerStatusMISSED
+	if checkedAt.Unix() > nextPrayer.UnixTime {
+		return repository.PrayerStatusMISSED, nil
 	} else if distanceToNextPrayer-int64(distanceQuarter) > 0 {
-		prayerStatus = repository.PrayerStatusONTIME
-	} else {
-		prayerStatus = repository.PrayerStatusLATE
+		return repository.PrayerStatusONTIME, nil
 	}
+	return repository.PrayerStatusLATE, nil
+}
 
-	prayerID := chi.URLParam(req, "prayerID")
-	prayerIDBytes, err := uuid.Parse(prayerID)
+func updatePrayerHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	var body struct {
+		PrayerName     string `json:"prayer_name" validate:"required"`
+		PrayerUnixTime int64  `json:"prayer_unix_time" validate:"required"`
+		CheckedAt      int64  `json:"checked_at" validate:"required"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to parse prayer uuid string to bytes")
-		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
 
-	tx, err := services.DB.Begin(ctx)
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	prayerLocation, adjustment, err := getUserPrayerLocation(ctx, userID)
 	if err != nil {
-		errMsg := "failed to begin tx to update prayer status and/or delete last prayer reminder"
-		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg(errMsg)
-		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		if errors.Is(err, errTimeZoneNotSet) || errors.Is(err, errPrayerCalendarNotReady) {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusConflict).Send()
+			http.Error(res, http.StatusText(http.StatusConflict), http.StatusConflict)
+		} else {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user prayer location")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
 		return
 	}
 
-	qtx := services.Queries.WithTx(tx)
-	err = qtx.UpdatePrayerStatus(ctx, repository.UpdatePrayerStatusParams{
-		ID:     pgtype.UUID{Bytes: prayerIDBytes, Valid: true},
-		Status: repository.NullPrayerStatus{PrayerStatus: prayerStatus, Valid: true},
-	})
+	prayerStatus, err := getPrayerStatus(ctx, prayerLocation, adjustment, prayer.Prayer{
+		Name:     body.PrayerName,
+		UnixTime: body.PrayerUnixTime,
+	}, time.Unix(body.CheckedAt, 0))
 
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update prayer status")
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get prayer status")
 		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	userID := fmt.Sprintf("%s", ctx.Value("userID"))
-	asynqTaskID := task.MakeLastPrayerReminderTaskID(userID, body.PrayerName)
-
-	if body.AccountType == repository.AccountTypePREMIUM {
-		err := services.AsynqInspector.DeleteTask(task.DefaultQueue, asynqTaskID)
-		isNotQueueNotFound := errors.Is(err, asynq.ErrQueueNotFound) == false
-		isNotTaskNotFound := errors.Is(err, asynq.ErrTaskNotFound) == false
-
-		if err != nil && isNotQueueNotFound && isNotTaskNotFound {
-			logWithCtx.
-				Error().
-				Err(err).
-				Caller().
-				Int("status_code", http.StatusInternalServerError).
-				Str("task_id", asynqTaskID).
-				Msg("failed to delete last prayer reminder")
-
-			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
+	prayerID := chi.URLParam(req, "prayerID")
+	prayerIDBytes, err := uuid.Parse(prayerID)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to parse prayer uuid string to bytes")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		errMsg := "failed to commit db tx to update prayer status and/or delete last prayer reminder"
-		logWithCtx.
-			Error().
-			Err(err).
-			Caller().
-			Int("status_code", http.StatusInternalServerError).
-			Str("task_id", asynqTaskID).
-			Msg(errMsg)
+	err = services.Queries.UpdatePrayerStatus(ctx, repository.UpdatePrayerStatusParams{
+		ID:     pgtype.UUID{Bytes: prayerIDBytes, Valid: true},
+		Status: repository.NullPrayerStatus{PrayerStatus: prayerStatus, Valid: true},
+	})
 
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update prayer status")
 		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,263 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	publicPrayerTimesMaxDays = 62
+	// The responses are private so no shared cache serves them to callers
+	// without an api key, past the quota.
+	publicCacheControl = "private, max-age=3600"
+)
+
+type publicPrayerDayRespBody struct {
+	Date      string               `json:"date"`
+	HijriDate prayer.HijriDate     `json:"hijri_date"`
+	Times     []prayerTimeRespBody `json:"times"`
+}
+
+type publicPrayerTimesRespBody struct {
+	City      string                    `json:"city,omitempty"`
+	Latitude  float64                   `json:"latitude"`
+	Longitude float64                   `json:"longitude"`
+	TimeZone  string                    `json:"time_zone"`
+	AsrMethod prayer.AsrMethod          `json:"asr_method"`
+	Days      []publicPrayerDayRespBody `json:"days"`
+}
+
+var errInvalidQueryParams = errors.New("invalid query params")
+
+func parseCoordinate(value string, limit float64) (float64, error) {
+	coordinate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.Wrap(errInvalidQueryParams, err.Error())
+	}
+
+	if math.IsNaN(coordinate) || math.IsInf(coordinate, 0) {
+		return 0, errors.Wrap(errInvalidQueryParams, fmt.Sprintf("coordinate is not a number: %s", value))
+	}
+
+	if coordinate < -limit || coordinate > limit {
+		return 0, errors.Wrap(errInvalidQueryParams, fmt.Sprintf("coordinate out of range: %s", value))
+	}
+	return coordinate, nil
+}
+
+func parseDateParam(value string, location *time.Location, defaultDate time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultDate, nil
+	}
+
+	date, err := time.ParseInLocation(time.DateOnly, value, location)
+	if err != nil {
+		return time.Time{}, errors.Wrap(errInvalidQueryParams, err.Error())
+	}
+	return date, nil
+}
+
+type publicPrayerTimesParams struct {
+	prayerLocation prayer.Location
+	adjustment     prayer.Adjustment
+	location       *time.Location
+	from           time.Time
+	days           int
+	format         string
+}
+
+func parsePublicPrayerTimesParams(req *http.Request) (publicPrayerTimesParams, error) {
+	query := req.URL.Query()
+	if query.Get("latitude") == "" || query.Get("longitude") == "" || query.Get("time_zone") == "" {
+		return publicPrayerTimesParams{}, errors.Wrap(errInvalidQueryParams, "missing required query params")
+	}
+
+	latitude, err := parseCoordinate(query.Get("latitude"), 90)
+	if err != nil {
+		return publicPrayerTimesParams{}, err
+	}
+
+	longitude, err := parseCoordinate(query.Get("longitude"), 180)
+	if err != nil {
+		return publicPrayerTimesParams{}, err
+	}
+
+	location, err := time.LoadLocation(query.Get("time_zone"))
+	if err != nil {
+		return publicPrayerTimesParams{}, errors.Wrap(errInvalidQueryParams, err.Error())
+	}
+
+	asrMethod := prayer.AsrMethod(query.Get("asr_method"))
+	if asrMethod == "" {
+		asrMethod = prayer.ShafiiAsrMethod
+	}
+
+	if asrMethod != prayer.ShafiiAsrMethod && asrMethod != prayer.HanafiAsrMethod {
+		return publicPrayerTimesParams{}, errors.Wrap(errInvalidQueryParams, fmt.Sprintf("unknown asr method: %s", asrMethod))
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if format != "json" && format != "csv" {
+		return publicPrayerTimesParams{}, errors.Wrap(errInvalidQueryParams, fmt.Sprintf("unknown format: %s", format))
+	}
+
+	now := services.Clock.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	from, err := parseDateParam(query.Get("from"), location, today)
+	if err != nil {
+		return publicPrayerTimesParams{}, err
+	}
+
+	to, err := parseDateParam(query.Get("to"), location, from)
+	if err != nil {
+		return publicPrayerTimesParams{}, err
+	}
+
+	// Counting calendar days instead of dividing durations keeps DST
+	// transitions from shortening the range.
+	days := 0
+	for date := from; date.After(to) == false; date = date.AddDate(0, 0, 1) {
+		days++
+		if days > publicPrayerTimesMaxDays {
+			return publicPrayerTimesParams{}, errors.Wrap(errInvalidQueryParams, fmt.Sprintf("date range exceeds %d days", publicPrayerTimesMaxDays))
+		}
+	}
+
+	if days == 0 {
+		return publicPrayerTimesParams{}, errors.Wrap(errInvalidQueryParams, "to is before from")
+	}
+
+	return publicPrayerTimesParams{
+		prayerLocation: prayer.NewLocation(query.Get("city"), latitude, longitude, location.String()),
+		adjustment:     prayer.Adjustment{AsrMethod: asrMethod},
+		location:       location,
+		from:           from,
+		days:           days,
+		format:         format,
+	}, nil
+}
+
+func writePrayerTimesCSV(prayerCalendar prayer.PrayerCalendar, location *time.Location) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	err := writer.Write([]string{"date", "name", "time", "unix_time"})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write csv header")
+	}
+
+	for _, prayers := range prayerCalendar {
+		for _, v := range prayers {
+			prayerTime := time.Unix(v.UnixTime, 0).In(location)
+			err = writer.Write([]string{
+				prayerTime.Format(time.DateOnly),
+				v.Name,
+				prayerTime.Format(time.RFC3339),
+				strconv.FormatInt(v.UnixTime, 10),
+			})
+
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to write csv record")
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, errors.Wrap(err, "failed to flush csv writer")
+	}
+	return buffer.Bytes(), nil
+}
+
+func getPublicPrayerTimesHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	params, err := parsePublicPrayerTimesParams(req)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Send()
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	prayerCalendar, err := getFeedPrayers(ctx, params.prayerLocation, params.adjustment, params.from, params.days)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get feed prayers")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var respBody []byte
+	contentType := "application/json"
+	if params.format == "csv" {
+		contentType = "text/csv; charset=utf-8"
+		respBody, err = writePrayerTimesCSV(prayerCalendar, params.location)
+	} else {
+		body := publicPrayerTimesRespBody{
+			City:      params.prayerLocation.City,
+			Latitude:  params.prayerLocation.Latitude,
+			Longitude: params.prayerLocation.Longitude,
+			TimeZone:  params.prayerLocation.TimeZone,
+			AsrMethod: params.adjustment.AsrMethod,
+			Days:      make([]publicPrayerDayRespBody, len(prayerCalendar)),
+		}
+
+		for i, prayers := range prayerCalendar {
+			date := params.from.AddDate(0, 0, i)
+			body.Days[i] = publicPrayerDayRespBody{
+				Date:      date.Format(time.DateOnly),
+				HijriDate: services.HijriCalendar.FromGregorian(date),
+				Times:     make([]prayerTimeRespBody, 0, len(prayers)),
+			}
+
+			for _, v := range prayers {
+				body.Days[i].Times = append(body.Days[i].Times, prayerTimeRespBody{Name: v.Name, UnixTime: v.UnixTime})
+			}
+		}
+		respBody, err = json.Marshal(&body)
+	}
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to encode prayer times")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	hash := sha256.Sum256(respBody)
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:16]))
+	res.Header().Set("Cache-Control", publicCacheControl)
+	res.Header().Set("ETag", etag)
+	res.Header().Set("Vary", "X-API-Key")
+
+	if req.Header.Get("If-None-Match") == etag {
+		res.WriteHeader(http.StatusNotModified)
+		logWithCtx.Info().Int("status_code", http.StatusNotModified).Dur("response_time", time.Since(start)).Msg("request completed")
+		return
+	}
+
+	res.Header().Set("Content-Type", contentType)
+	res.WriteHeader(http.StatusOK)
+	_, err = res.Write(respBody)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to write prayer times")
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
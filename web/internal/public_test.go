@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNonFiniteCoordinates(t *testing.T) {
+	handlers := []struct {
+		name    string
+		path    string
+		handler http.HandlerFunc
+	}{
+		{name: "public prayer times", path: "/public/v1/prayer-times", handler: getPublicPrayerTimesHandler},
+		{name: "qibla", path: "/qibla", handler: getQiblaHandler},
+	}
+
+	queries := []string{
+		"latitude=NaN&longitude=106.8",
+		"latitude=-6.2&longitude=nan",
+		"latitude=Inf&longitude=106.8",
+		"latitude=-6.2&longitude=-Inf",
+		"latitude=+Infinity&longitude=106.8",
+	}
+
+	for _, v := range handlers {
+		for _, query := range queries {
+			t.Run(v.name+"?"+query, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, v.path+"?"+query+"&time_zone=Asia/Jakarta", nil)
+				res := httptest.NewRecorder()
+				v.handler(res, req)
+
+				if res.Code != http.StatusBadRequest {
+					t.Errorf("status code = %d, want %d", res.Code, http.StatusBadRequest)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+type qiblaRespBody struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Direction float64 `json:"direction"`
+	Distance  float64 `json:"distance"`
+}
+
+func getQiblaHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	latitudeString := req.URL.Query().Get("latitude")
+	longitudeString := req.URL.Query().Get("longitude")
+
+	var latitude, longitude float64
+	if latitudeString != "" || longitudeString != "" {
+		var err error
+		latitude, err = parseCoordinate(latitudeString, 90)
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Send()
+			http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		longitude, err = parseCoordinate(longitudeString, 180)
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Send()
+			http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	} else {
+		userID := fmt.Sprintf("%s", ctx.Value("userID"))
+		userLocation, err := services.Queries.GetUserLocationByID(ctx, userID)
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user location by id")
+			http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if userLocation.Latitude.Valid == false || userLocation.Longitude.Valid == false {
+			err := errors.New("user location is not set")
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusConflict).Send()
+			http.Error(res, http.StatusText(http.StatusConflict), http.StatusConflict)
+			return
+		}
+
+		latitude = userLocation.Latitude.Float64
+		longitude = userLocation.Longitude.Float64
+	}
+
+	qibla := prayer.GetQibla(latitude, longitude)
+	respBody := qiblaRespBody{
+		Latitude:  latitude,
+		Longitude: longitude,
+		Direction: qibla.Direction,
+		Distance:  qibla.Distance,
+	}
+
+	err := sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusOK, Data: &respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
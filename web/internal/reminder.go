@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/web/configs/services"
+	"github.com/mdayat/demi-masa/web/repository"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+var fardhuPrayerNames = []string{
+	prayer.SubuhPrayerName,
+	prayer.ZuhurPrayerName,
+	prayer.AsarPrayerName,
+	prayer.MagribPrayerName,
+	prayer.IsyaPrayerName,
+}
+
+type reminderPreferenceRespBody struct {
+	PrayerName          string `json:"prayer_name"`
+	Enabled             bool   `json:"enabled"`
+	LeadMinutes         int    `json:"lead_minutes"`
+	LastReminderPercent int    `json:"last_reminder_percent"`
+	Channel             string `json:"channel,omitempty"`
+}
+
+// getReminderPreferences returns the preference of every fardhu prayer,
+// prayers the user never changed get the default one.
+func getReminderPreferences(ctx context.Context, userID string) (map[string]prayer.ReminderPreference, error) {
+	rows, err := services.Queries.GetReminderPreferences(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get reminder preferences")
+	}
+
+	reminderPreferences := make(map[string]prayer.ReminderPreference, len(fardhuPrayerNames))
+	for _, name := range fardhuPrayerNames {
+		reminderPreferences[name] = prayer.DefaultReminderPreference
+	}
+
+	for _, v := range rows {
+		reminderPreferences[v.PrayerName] = prayer.ReminderPreference{
+			Enabled:             v.Enabled,
+			LeadMinutes:         int(v.LeadMinutes),
+			LastReminderPercent: int(v.LastReminderPercent),
+			Channel:             v.Channel.String,
+		}
+	}
+
+	return reminderPreferences, nil
+}
+
+func getReminderPreferencesHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	reminderPreferences, err := getReminderPreferences(ctx, userID)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get reminder preferences")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respBody := make([]reminderPreferenceRespBody, 0, len(fardhuPrayerNames))
+	for _, name := range fardhuPrayerNames {
+		reminderPreference := reminderPreferences[name]
+		respBody = append(respBody, reminderPreferenceRespBody{
+			PrayerName:          name,
+			Enabled:             reminderPreference.Enabled,
+			LeadMinutes:         reminderPreference.LeadMinutes,
+			LastReminderPercent: reminderPreference.LastReminderPercent,
+			Channel:             reminderPreference.Channel,
+		})
+	}
+
+	err = sendJSONSuccessResponse(res, successResponseParams{StatusCode: http.StatusOK, Data: &respBody})
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to send successful response body")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updateReminderPreferenceHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+
+	prayerName := chi.URLParam(req, "prayerName")
+	if prayer.IsFardhu(prayerName) == false {
+		err := errors.New(fmt.Sprintf("unknown prayer name: %s", prayerName))
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusNotFound).Send()
+		http.Error(res, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		Enabled             bool   `json:"enabled"`
+		LeadMinutes         int    `json:"lead_minutes"`
+		LastReminderPercent int    `json:"last_reminder_percent"`
+		Channel             string `json:"channel" validate:"omitempty,oneof=WHATSAPP SMS EMAIL PUSH"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err == nil {
+		err = prayer.ReminderPreference{
+			LeadMinutes:         body.LeadMinutes,
+			LastReminderPercent: body.LastReminderPercent,
+		}.Validate()
+	}
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpsertReminderPreference(ctx, repository.UpsertReminderPreferenceParams{
+		UserID:              userID,
+		PrayerName:          prayerName,
+		Enabled:             body.Enabled,
+		LeadMinutes:         int16(body.LeadMinutes),
+		LastReminderPercent: int16(body.LastReminderPercent),
+		Channel:             pgtype.Text{String: body.Channel, Valid: body.Channel != ""},
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to upsert reminder preference")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
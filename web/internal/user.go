@@ -2,14 +2,17 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
-	"github.com/avast/retry-go/v4"
 	"github.com/go-chi/chi/v5"
 	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/pkg/notifier"
 	"github.com/mdayat/demi-masa/pkg/prayer"
 	"github.com/mdayat/demi-masa/pkg/task"
 	"github.com/mdayat/demi-masa/web/configs/services"
@@ -38,117 +41,116 @@ func deleteUserHandler(res http.ResponseWriter, req *http.Request) {
 	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
 }
 
-func addUserToTaskQueue(ctx context.Context) (nextPrayer prayer.Prayer, err error) {
-	timeZone := fmt.Sprintf("%s", ctx.Value("time_zone"))
-	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+type locationBody struct {
+	City      string  `json:"city" validate:"required"`
+	Latitude  float64 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" validate:"min=-180,max=180"`
+}
 
-	prayerCalendar, err := prayer.GetPrayerCalendar(ctx, services.RedisClient, timeZone)
-	if err != nil {
-		return nextPrayer, errors.Wrap(err, "failed to get prayer calendar")
-	}
+var (
+	errLocationRequired    = errors.New("time zone requires user location")
+	errLocationUnsupported = errors.New("prayer times of user location cannot be calculated all year")
+)
 
-	lastDayPrayer, err := prayer.GetLastDayPrayer(ctx, services.RedisClient, timeZone)
+// checkLocation turns away a location of polar day or night, the worker has
+// no prayer times to remind its users of for part of the year.
+func checkLocation(location prayer.Location) error {
+	err := prayer.CheckSunriseAndSunset(location.Latitude, location.Longitude, services.Clock.Now().Year())
 	if err != nil {
-		return nextPrayer, errors.Wrap(err, "failed to get last day prayer")
+		return errors.Wrap(errLocationUnsupported, err.Error())
 	}
+	return nil
+}
 
-	location, err := time.LoadLocation(timeZone)
+func enqueuePrayerCalendarInit(payload task.PrayerCalendarInitPayload) error {
+	asynqTask, err := task.NewPrayerCalendarInitTask(payload)
 	if err != nil {
-		return nextPrayer, errors.Wrap(err, "failed to load time zone location")
+		return errors.Wrap(err, "failed to create prayer calendar init task")
 	}
 
-	now := time.Now().In(location)
-	currentDay := now.Day()
-	currentUnixTime := now.Unix()
-
-	isLastDay := prayer.IsLastDay(now)
-	isPenultimateDay := prayer.IsPenultimateDay(now)
-	isyaPrayer := prayerCalendar[currentDay-1][5]
-
-	if isLastDay {
-		isyaPrayer = lastDayPrayer[5]
+	_, err = services.AsynqClient.Enqueue(asynqTask)
+	if err != nil && errors.Is(err, asynq.ErrTaskIDConflict) == false {
+		return errors.Wrap(err, "failed to enqueue prayer calendar init task")
 	}
 
-	var isNextPrayerLastDay bool
-	if (isPenultimateDay && currentUnixTime > isyaPrayer.UnixTime) ||
-		(isLastDay && currentUnixTime < isyaPrayer.UnixTime) {
-		isNextPrayerLastDay = true
-	}
-
-	if isLastDay && currentUnixTime < isyaPrayer.UnixTime {
-		nextPrayer = prayer.GetNextPrayer(prayerCalendar, lastDayPrayer, currentDay, currentUnixTime)
-	} else {
-		if isLastDay && currentUnixTime > isyaPrayer.UnixTime {
-			currentDay = 1
-		}
+	return nil
+}
 
-		nextPrayer = prayer.GetNextPrayer(prayerCalendar, nil, currentDay, currentUnixTime)
+// updateTimeZone points the user at the prayer calendar of their location,
+// whose calendar and reminder fan-out the worker initializes. Time zones
+// without a fallback location need the user's own location.
+func updateTimeZone(ctx context.Context, userID string, location *locationBody) error {
+	userLocation, err := services.Queries.GetUserLocationByID(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get user location by id")
 	}
 
-	asynqTask, err := task.NewPrayerReminderTask(task.PrayerReminderPayload{
-		UserID:         userID,
-		PrayerName:     nextPrayer.Name,
-		PrayerUnixTime: nextPrayer.UnixTime,
-		IsLastDay:      isNextPrayerLastDay,
-	})
-
-	if err != nil {
-		return nextPrayer, errors.Wrap(err, "failed to create prayer reminder task")
+	timeZone := fmt.Sprintf("%s", ctx.Value("time_zone"))
+	var ownLocation *prayer.Location
+	if location != nil {
+		prayerLocation := prayer.NewLocation(location.City, location.Latitude, location.Longitude, timeZone)
+		ownLocation = &prayerLocation
+	} else if userLocation.Latitude.Valid && userLocation.Longitude.Valid {
+		prayerLocation := prayer.NewLocation(
+			userLocation.City.String,
+			userLocation.Latitude.Float64,
+			userLocation.Longitude.Float64,
+			timeZone,
+		)
+		ownLocation = &prayerLocation
 	}
 
-	duration := time.Duration(nextPrayer.UnixTime-currentUnixTime) * time.Second
-	_, err = services.AsynqClient.Enqueue(asynqTask, asynq.ProcessIn(duration))
-	if err != nil {
-		return nextPrayer, errors.Wrap(err, "failed to enqueue prayer reminder task")
+	prayerLocation, err := prayer.GetTimeZoneLocation(timeZone)
+	if err != nil && ownLocation == nil {
+		return errLocationRequired
 	}
 
-	return nextPrayer, nil
-}
+	if ownLocation != nil {
+		err = checkLocation(*ownLocation)
+		if err != nil {
+			return err
+		}
+		prayerLocation = *ownLocation
+	}
 
-func updateTimeZone(ctx context.Context, userID string) error {
 	tx, err := services.DB.Begin(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to start db tx")
 	}
+	defer tx.Rollback(ctx)
 
 	qtx := services.Queries.WithTx(tx)
-	userTimeZone, err := qtx.GetUserTimeZoneByID(ctx, userID)
-	if err != nil {
-		return errors.Wrap(err, "failed to get user time zone by id")
-	}
-
-	timeZone := fmt.Sprintf("%s", ctx.Value("time_zone"))
 	err = qtx.UpdateUserTimeZone(ctx, repository.UpdateUserTimeZoneParams{
-		ID:       userID,
-		TimeZone: repository.NullIndonesiaTimeZone{IndonesiaTimeZone: repository.IndonesiaTimeZone(timeZone), Valid: true}},
-	)
+		ID:          userID,
+		TimeZone:    pgtype.Text{String: timeZone, Valid: true},
+		LocationKey: pgtype.Text{String: prayerLocation.Key(), Valid: true},
+	})
 	if err != nil {
 		return errors.Wrap(err, "failed to update user time zone")
 	}
 
-	var nextPrayer prayer.Prayer
-	if userTimeZone.Valid == false {
-		nextPrayer, err = addUserToTaskQueue(ctx)
+	if location != nil {
+		err = qtx.UpdateUserLocation(ctx, repository.UpdateUserLocationParams{
+			ID:          userID,
+			City:        pgtype.Text{String: location.City, Valid: true},
+			Latitude:    pgtype.Float8{Float64: location.Latitude, Valid: true},
+			Longitude:   pgtype.Float8{Float64: location.Longitude, Valid: true},
+			LocationKey: pgtype.Text{String: prayerLocation.Key(), Valid: true},
+		})
+
 		if err != nil {
-			return errors.Wrap(err, "failed to add user to task queue")
+			return errors.Wrap(err, "failed to update user location")
 		}
 	}
 
 	err = tx.Commit(ctx)
-	if err != nil && userTimeZone.Valid {
+	if err != nil {
 		return errors.Wrap(err, "failed to commit db tx")
 	}
 
-	if err != nil && userTimeZone.Valid == false {
-		return retry.Do(func() error {
-			asynqTaskID := task.MakePrayerReminderTaskID(userID, nextPrayer.Name)
-			err = services.AsynqInspector.DeleteTask(task.DefaultQueue, asynqTaskID)
-			if err != nil {
-				return errors.Wrap(err, "failed to delete prayer reminder")
-			}
-
-			return nil
-		})
+	err = enqueuePrayerCalendarInit(task.PrayerCalendarInitPayload{Location: prayerLocation})
+	if err != nil {
+		return errors.Wrap(err, "failed to enqueue prayer calendar init")
 	}
 
 	return nil
@@ -159,7 +161,8 @@ func updateTimeZoneHandler(res http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 	logWithCtx := log.Ctx(ctx).With().Logger()
 	var body struct {
-		TimeZone repository.IndonesiaTimeZone `json:"time_zone" validate:"required"`
+		TimeZone string        `json:"time_zone" validate:"required,timezone"`
+		Location *locationBody `json:"location"`
 	}
 
 	err := decodeAndValidateJSONBody(req, &body)
@@ -170,17 +173,271 @@ func updateTimeZoneHandler(res http.ResponseWriter, req *http.Request) {
 	}
 
 	userID := fmt.Sprintf("%s", ctx.Value("userID"))
-	err = updateTimeZone(context.WithValue(ctx, "time_zone", body.TimeZone), userID)
+	err = updateTimeZone(context.WithValue(ctx, "time_zone", body.TimeZone), userID, body.Location)
 	if err != nil {
+		if errors.Is(err, errLocationRequired) || errors.Is(err, errLocationUnsupported) {
+			logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusUnprocessableEntity).Str("time_zone", body.TimeZone).Send()
+			http.Error(res, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+			return
+		}
+
 		logWithCtx.
 			Error().
 			Err(err).
 			Caller().
 			Int("status_code", http.StatusInternalServerError).
 			Str("user_id", userID).
-			Str("time_zone", string(body.TimeZone)).
-			Msg("failed to update user time zone and add user to task queue")
+			Str("time_zone", body.TimeZone).
+			Msg("failed to update user time zone")
+
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updateLocationHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body locationBody
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
 
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	userTimeZone, err := services.Queries.GetUserTimeZoneByID(ctx, userID)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to get user time zone by id")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if userTimeZone.Valid == false {
+		logWithCtx.Error().Err(errTimeZoneNotSet).Caller().Int("status_code", http.StatusConflict).Send()
+		http.Error(res, http.StatusText(http.StatusConflict), http.StatusConflict)
+		return
+	}
+
+	prayerLocation := prayer.NewLocation(body.City, body.Latitude, body.Longitude, userTimeZone.String)
+	err = checkLocation(prayerLocation)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusUnprocessableEntity).Str("location", prayerLocation.Key()).Send()
+		http.Error(res, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = services.Queries.UpdateUserLocation(ctx, repository.UpdateUserLocationParams{
+		ID:          userID,
+		City:        pgtype.Text{String: body.City, Valid: true},
+		Latitude:    pgtype.Float8{Float64: body.Latitude, Valid: true},
+		Longitude:   pgtype.Float8{Float64: body.Longitude, Valid: true},
+		LocationKey: pgtype.Text{String: prayerLocation.Key(), Valid: true},
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update user location")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	err = enqueuePrayerCalendarInit(task.PrayerCalendarInitPayload{Location: prayerLocation})
+	if err != nil {
+		logWithCtx.
+			Error().
+			Err(err).
+			Caller().
+			Int("status_code", http.StatusInternalServerError).
+			Str("location", prayerLocation.Key()).
+			Msg("failed to enqueue prayer calendar init task")
+
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updateSunnahReminderHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		Enabled bool `json:"enabled"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpdateUserSunnahReminder(ctx, repository.UpdateUserSunnahReminderParams{
+		ID:             userID,
+		SunnahReminder: body.Enabled,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update user sunnah reminder")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updatePrayerSettingsHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		AsrMethod repository.AsrMethod `json:"asr_method" validate:"required,oneof=SHAFII HANAFI"`
+		Offsets   map[string]int       `json:"offsets"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err == nil {
+		err = prayer.ValidateOffsets(body.Offsets)
+	}
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if body.Offsets == nil {
+		body.Offsets = map[string]int{}
+	}
+
+	offsets, err := json.Marshal(body.Offsets)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to marshal prayer offsets")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpdateUserPrayerSettings(ctx, repository.UpdateUserPrayerSettingsParams{
+		ID:            userID,
+		AsrMethod:     body.AsrMethod,
+		PrayerOffsets: offsets,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update user prayer settings")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updateNotificationChannelsHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		Channels []notifier.Channel `json:"channels" validate:"required,min=1,unique,dive,oneof=WHATSAPP SMS EMAIL PUSH"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	channels, err := json.Marshal(body.Channels)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to marshal notification channels")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpdateUserNotificationChannels(ctx, repository.UpdateUserNotificationChannelsParams{
+		ID:                   userID,
+		NotificationChannels: channels,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update user notification channels")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updateQuietHoursHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		Windows []prayer.QuietWindow `json:"windows" validate:"required"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	err = prayer.ValidateQuietWindows(body.Windows)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid quiet windows")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	quietWindows, err := json.Marshal(body.Windows)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to marshal quiet windows")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpdateUserQuietWindows(ctx, repository.UpdateUserQuietWindowsParams{
+		ID:           userID,
+		QuietWindows: quietWindows,
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update user quiet windows")
+		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	logWithCtx.Info().Int("status_code", http.StatusOK).Dur("response_time", time.Since(start)).Msg("request completed")
+}
+
+func updateLocaleHandler(res http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	ctx := req.Context()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var body struct {
+		Locale message.Locale `json:"locale" validate:"required,oneof=id en jv su"`
+	}
+
+	err := decodeAndValidateJSONBody(req, &body)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusBadRequest).Msg("invalid request body")
+		http.Error(res, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	userID := fmt.Sprintf("%s", ctx.Value("userID"))
+	err = services.Queries.UpdateUserLocale(ctx, repository.UpdateUserLocaleParams{
+		ID:     userID,
+		Locale: string(body.Locale),
+	})
+
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Int("status_code", http.StatusInternalServerError).Msg("failed to update user locale")
 		http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 
@@ -56,3 +59,19 @@ func decodeAndValidateJSONBody(req *http.Request, dst interface{}) error {
 
 	return nil
 }
+
+func generateSecretToken() (string, error) {
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read random bytes")
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Only the hash of a secret token is stored, the token itself is shown
+// once when it is issued.
+func makeSecretTokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	_ "time/tzdata"
 
+	"github.com/mdayat/demi-masa/pkg/notifier"
 	"github.com/mdayat/demi-masa/web/configs/env"
 	"github.com/mdayat/demi-masa/web/configs/services"
 	"github.com/mdayat/demi-masa/web/internal"
@@ -46,6 +47,27 @@ func main() {
 	defer asynqInspector.Close()
 
 	services.InitTwilio(env.TWILIO_ACCOUNT_SID, env.TWILIO_AUTH_TOKEN)
+	err = services.InitNotifier(notifier.Config{
+		ChannelNames:         env.NOTIFICATION_CHANNELS,
+		TwilioClient:         services.TwilioClient,
+		WhatsAppSender:       env.TWILIO_SENDER,
+		SMSSender:            env.TWILIO_SMS_SENDER,
+		TwilioStatusCallback: env.TWILIO_STATUS_CALLBACK_URL,
+	})
+
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	err = services.InitHijriCalendar(env.HIJRI_OFFSETS)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	err = services.InitMessageStore()
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
 
 	app := internal.InitApp()
 	err = http.ListenAndServe(":8080", app)
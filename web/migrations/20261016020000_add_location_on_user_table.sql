@@ -0,0 +1,2 @@
+-- Modify "user" table
+ALTER TABLE "user" ADD COLUMN "city" character varying(255) NULL, ADD COLUMN "latitude" double precision NULL, ADD COLUMN "longitude" double precision NULL;
@@ -0,0 +1,12 @@
+-- Create "prayer_calendar" table
+CREATE TABLE "prayer_calendar" (
+  "location_key" character varying(255) NOT NULL,
+  "date" date NOT NULL,
+  "latitude" double precision NOT NULL,
+  "longitude" double precision NOT NULL,
+  "time_zone" character varying(255) NOT NULL,
+  "provider" character varying(255) NOT NULL,
+  "prayers" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("location_key", "date")
+);
@@ -0,0 +1,2 @@
+-- Modify "user" table
+ALTER TABLE "user" ADD COLUMN "sunnah_reminder" boolean NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+-- Create enum type "asr_method"
+CREATE TYPE "asr_method" AS ENUM ('SHAFII', 'HANAFI');
+-- Modify "user" table
+ALTER TABLE "user" ADD COLUMN "asr_method" "asr_method" NOT NULL DEFAULT 'SHAFII', ADD COLUMN "prayer_offsets" jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- Modify "user" table
+ALTER TABLE "user" ALTER COLUMN "time_zone" TYPE character varying(255);
+-- Drop enum type "indonesia_time_zone"
+DROP TYPE "indonesia_time_zone";
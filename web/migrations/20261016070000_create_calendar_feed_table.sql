@@ -0,0 +1,10 @@
+-- Create "calendar_feed" table
+CREATE TABLE "calendar_feed" (
+  "user_id" character varying(255) NOT NULL,
+  "token_hash" character varying(255) NOT NULL,
+  "include_tasks" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("user_id"),
+  CONSTRAINT "calendar_feed_token_hash_key" UNIQUE ("token_hash"),
+  CONSTRAINT "fk_user_calendar_feed" FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,12 @@
+-- Create "api_key" table
+CREATE TABLE "api_key" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" character varying(255) NOT NULL,
+  "name" character varying(255) NOT NULL,
+  "key_hash" character varying(255) NOT NULL,
+  "daily_quota" integer NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "api_key_key_hash_key" UNIQUE ("key_hash"),
+  CONSTRAINT "fk_user_api_key" FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
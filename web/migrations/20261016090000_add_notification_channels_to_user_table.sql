@@ -0,0 +1,2 @@
+-- Modify "user" table
+ALTER TABLE "user" ADD COLUMN "notification_channels" jsonb NOT NULL DEFAULT '["WHATSAPP"]';
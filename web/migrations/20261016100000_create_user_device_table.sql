@@ -0,0 +1,11 @@
+-- Create "user_device" table
+CREATE TABLE "user_device" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" character varying(255) NOT NULL,
+  "token" character varying(512) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_device_token_key" UNIQUE ("token"),
+  CONSTRAINT "fk_user_user_device" FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
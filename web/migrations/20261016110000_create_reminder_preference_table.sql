@@ -0,0 +1,11 @@
+-- Create "reminder_preference" table
+CREATE TABLE "reminder_preference" (
+  "user_id" character varying(255) NOT NULL,
+  "prayer_name" character varying(50) NOT NULL,
+  "enabled" boolean NOT NULL DEFAULT true,
+  "lead_minutes" smallint NOT NULL DEFAULT 0,
+  "last_reminder_percent" smallint NOT NULL DEFAULT 75,
+  "channel" character varying(50) NULL,
+  PRIMARY KEY ("user_id", "prayer_name"),
+  CONSTRAINT "fk_user_reminder_preference" FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
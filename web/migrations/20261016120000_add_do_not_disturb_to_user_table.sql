@@ -0,0 +1,2 @@
+-- Modify "user" table
+ALTER TABLE "user" ADD COLUMN "quiet_windows" jsonb NOT NULL DEFAULT '[]', ADD COLUMN "reminders_paused_until" timestamptz NULL;
@@ -0,0 +1,11 @@
+-- Modify "user" table
+ALTER TABLE "user" ADD COLUMN "locale" character varying(10) NOT NULL DEFAULT 'id';
+-- Create "message_template" table
+CREATE TABLE "message_template" (
+  "kind" character varying(100) NOT NULL,
+  "locale" character varying(10) NOT NULL,
+  "subject" character varying(255) NOT NULL,
+  "body" text NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("kind", "locale")
+);
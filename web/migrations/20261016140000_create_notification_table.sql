@@ -0,0 +1,20 @@
+-- Create "notification" table
+CREATE TABLE "notification" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" character varying(255) NOT NULL,
+  "task_id" character varying(255) NULL,
+  "kind" character varying(100) NOT NULL,
+  "channel" character varying(50) NULL,
+  "provider_sid" character varying(64) NULL,
+  "status" character varying(50) NOT NULL,
+  "error" text NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "notification_provider_sid_key" UNIQUE ("provider_sid"),
+  CONSTRAINT "fk_user_notification" FOREIGN KEY ("user_id") REFERENCES "user" ("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
+-- Create index "idx_notification_task_id" to table: "notification"
+CREATE INDEX "idx_notification_task_id" ON "notification" ("task_id");
+-- Create index "idx_notification_user_id_created_at" to table: "notification"
+CREATE INDEX "idx_notification_user_id_created_at" ON "notification" ("user_id", "created_at");
@@ -0,0 +1,13 @@
+-- Modify "user" table
+ALTER TABLE "user" ADD COLUMN "location_key" character varying(32) NULL;
+-- Create index "idx_user_location_key_id" to table: "user"
+CREATE INDEX "idx_user_location_key_id" ON "user" ("location_key", "id");
+-- Backfill "location_key" of users with a time zone
+UPDATE "user" SET "location_key" = CASE
+  WHEN "latitude" IS NOT NULL AND "longitude" IS NOT NULL
+    THEN ROUND("latitude"::numeric, 1)::text || ',' || ROUND("longitude"::numeric, 1)::text
+  WHEN "time_zone" = 'Asia/Jakarta' THEN '-6.2,106.8'
+  WHEN "time_zone" = 'Asia/Makassar' THEN '-5.1,119.4'
+  WHEN "time_zone" = 'Asia/Jayapura' THEN '-2.5,140.7'
+END
+WHERE "time_zone" IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Modify "user" table
+ALTER TABLE "user" ALTER COLUMN "location_key" TYPE character varying(64);
+-- Backfill "location_key" with the time zone of the user
+UPDATE "user" SET "location_key" = "location_key" || ',' || "time_zone"
+WHERE "location_key" IS NOT NULL AND "time_zone" IS NOT NULL;
+-- Remove the prayer calendars stored under keys without a time zone
+DELETE FROM "prayer_calendar" WHERE "location_key" NOT LIKE '%,%,%';
@@ -7,6 +7,16 @@ SELECT * FROM "user" WHERE phone_number = $1;
 -- name: GetUserTimeZoneByID :one
 SELECT u.time_zone FROM "user" u WHERE u.id = $1;
 
+-- name: GetUserLocationByID :one
+SELECT
+  u.city,
+  u.latitude,
+  u.longitude,
+  u.time_zone,
+  u.asr_method,
+  u.prayer_offsets
+FROM "user" u WHERE u.id = $1;
+
 -- name: GetUserSubsByID :one
 SELECT u.account_type FROM "user" u WHERE u.id = $1;
 
@@ -17,7 +27,16 @@ UPDATE "user" SET phone_number = $2, phone_verified = $3 WHERE id = $1;
 UPDATE "user" SET account_type = $2 WHERE id = $1;
 
 -- name: UpdateUserTimeZone :exec
-UPDATE "user" SET time_zone = $2 WHERE id = $1;
+UPDATE "user" SET time_zone = $2, location_key = $3 WHERE id = $1;
+
+-- name: UpdateUserLocation :exec
+UPDATE "user" SET city = $2, latitude = $3, longitude = $4, location_key = $5 WHERE id = $1;
+
+-- name: UpdateUserSunnahReminder :exec
+UPDATE "user" SET sunnah_reminder = $2 WHERE id = $1;
+
+-- name: UpdateUserPrayerSettings :exec
+UPDATE "user" SET asr_method = $2, prayer_offsets = $3 WHERE id = $1;
 
 -- name: CreateUser :one
 INSERT INTO "user" (id, name, email) VALUES ($1, $2, $3) RETURNING *;
@@ -93,7 +112,8 @@ FROM prayer p WHERE p.user_id = $1 AND p.year = $2 AND p.month = $3 AND p.day =
 SELECT
   p.id,
   p.name,
-  p.status
+  p.status,
+  p.day
 FROM prayer p WHERE p.user_id = $1 AND p.year = $2 AND p.month = $3 AND p.status IS NOT NULL;
 
 -- name: CreatePrayers :copyfrom
@@ -102,3 +122,112 @@ VALUES ($1, $2, $3, $4, $5, $6);
 
 -- name: UpdatePrayerStatus :exec
 UPDATE prayer SET status = $2 WHERE id = $1;
+
+-- name: GetPrayerCalendar :many
+SELECT pc.date, pc.prayers FROM prayer_calendar pc
+WHERE pc.location_key = @location_key AND pc.date BETWEEN @from_date AND @to_date
+ORDER BY pc.date;
+
+-- name: GetCalendarFeedByTokenHash :one
+SELECT cf.user_id, cf.include_tasks FROM calendar_feed cf WHERE cf.token_hash = $1;
+
+-- name: UpsertCalendarFeed :exec
+INSERT INTO calendar_feed (user_id, token_hash, include_tasks) VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, include_tasks = EXCLUDED.include_tasks, created_at = CURRENT_TIMESTAMP;
+
+-- name: UpdateCalendarFeedIncludeTasks :execrows
+UPDATE calendar_feed SET include_tasks = $2 WHERE user_id = $1;
+
+-- name: DeleteCalendarFeed :exec
+DELETE FROM calendar_feed WHERE user_id = $1;
+
+-- name: GetAPIKeysByUserID :many
+SELECT
+  ak.id,
+  ak.name,
+  ak.daily_quota,
+  ak.created_at
+FROM api_key ak WHERE ak.user_id = $1 ORDER BY ak.created_at;
+
+-- name: CountAPIKeysByUserID :one
+SELECT COUNT(*) FROM api_key ak WHERE ak.user_id = $1;
+
+-- name: GetAPIKeyByHash :one
+SELECT ak.id, ak.daily_quota FROM api_key ak WHERE ak.key_hash = $1;
+
+-- name: CreateAPIKey :one
+INSERT INTO api_key (user_id, name, key_hash, daily_quota) VALUES ($1, $2, $3, $4)
+RETURNING id, name, daily_quota, created_at;
+
+-- name: DeleteAPIKey :execrows
+DELETE FROM api_key WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateUserNotificationChannels :exec
+UPDATE "user" SET notification_channels = $2 WHERE id = $1;
+
+-- name: UpsertUserDevice :exec
+INSERT INTO user_device (user_id, token) VALUES ($1, $2)
+ON CONFLICT (token) DO UPDATE SET user_id = EXCLUDED.user_id, updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteUserDevice :execrows
+DELETE FROM user_device WHERE user_id = $1 AND token = $2;
+
+-- name: GetReminderPreferences :many
+SELECT
+  rp.prayer_name,
+  rp.enabled,
+  rp.lead_minutes,
+  rp.last_reminder_percent,
+  rp.channel
+FROM reminder_preference rp WHERE rp.user_id = $1;
+
+-- name: UpsertReminderPreference :exec
+INSERT INTO reminder_preference (user_id, prayer_name, enabled, lead_minutes, last_reminder_percent, channel)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, prayer_name) DO UPDATE SET
+  enabled = EXCLUDED.enabled,
+  lead_minutes = EXCLUDED.lead_minutes,
+  last_reminder_percent = EXCLUDED.last_reminder_percent,
+  channel = EXCLUDED.channel;
+
+-- name: UpdateUserQuietWindows :exec
+UPDATE "user" SET quiet_windows = $2 WHERE id = $1;
+
+-- name: GetUserRemindersPausedUntilByID :one
+SELECT u.reminders_paused_until FROM "user" u WHERE u.id = $1;
+
+-- name: UpdateUserRemindersPausedUntil :exec
+UPDATE "user" SET reminders_paused_until = $2 WHERE id = $1;
+
+-- name: GetUserLocaleByID :one
+SELECT u.locale FROM "user" u WHERE u.id = $1;
+
+-- name: UpdateUserLocale :exec
+UPDATE "user" SET locale = $2 WHERE id = $1;
+
+-- name: GetMessageTemplate :one
+SELECT mt.subject, mt.body FROM message_template mt WHERE mt.kind = $1 AND mt.locale = $2;
+
+-- name: CreateNotification :exec
+INSERT INTO notification (user_id, task_id, kind, channel, provider_sid, status, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: UpdateNotificationStatus :execrows
+UPDATE notification SET status = @status, error = @error, updated_at = CURRENT_TIMESTAMP
+WHERE provider_sid = @provider_sid AND status = ANY(@from_statuses::VARCHAR[]);
+
+-- name: GetNotificationsByUserID :many
+SELECT
+  n.id,
+  n.kind,
+  n.channel,
+  n.status,
+  n.error,
+  n.created_at,
+  n.updated_at
+FROM notification n WHERE n.user_id = $1 AND n.created_at < $2
+ORDER BY n.created_at DESC LIMIT $3;
+
+-- name: GetPrayerByUserIDAndDate :one
+SELECT p.id, p.status FROM prayer p
+WHERE p.user_id = $1 AND p.name = $2 AND p.year = $3 AND p.month = $4 AND p.day = $5;
@@ -53,47 +53,46 @@ func (ns NullAccountType) Value() (driver.Value, error) {
 	return string(ns.AccountType), nil
 }
 
-type IndonesiaTimeZone string
+type AsrMethod string
 
 const (
-	IndonesiaTimeZoneAsiaJakarta  IndonesiaTimeZone = "Asia/Jakarta"
-	IndonesiaTimeZoneAsiaMakassar IndonesiaTimeZone = "Asia/Makassar"
-	IndonesiaTimeZoneAsiaJayapura IndonesiaTimeZone = "Asia/Jayapura"
+	AsrMethodSHAFII AsrMethod = "SHAFII"
+	AsrMethodHANAFI AsrMethod = "HANAFI"
 )
 
-func (e *IndonesiaTimeZone) Scan(src interface{}) error {
+func (e *AsrMethod) Scan(src interface{}) error {
 	switch s := src.(type) {
 	case []byte:
-		*e = IndonesiaTimeZone(s)
+		*e = AsrMethod(s)
 	case string:
-		*e = IndonesiaTimeZone(s)
+		*e = AsrMethod(s)
 	default:
-		return fmt.Errorf("unsupported scan type for IndonesiaTimeZone: %T", src)
+		return fmt.Errorf("unsupported scan type for AsrMethod: %T", src)
 	}
 	return nil
 }
 
-type NullIndonesiaTimeZone struct {
-	IndonesiaTimeZone IndonesiaTimeZone `json:"indonesia_time_zone"`
-	Valid             bool              `json:"valid"` // Valid is true if IndonesiaTimeZone is not NULL
+type NullAsrMethod struct {
+	AsrMethod AsrMethod `json:"asr_method"`
+	Valid     bool      `json:"valid"` // Valid is true if AsrMethod is not NULL
 }
 
 // Scan implements the Scanner interface.
-func (ns *NullIndonesiaTimeZone) Scan(value interface{}) error {
+func (ns *NullAsrMethod) Scan(value interface{}) error {
 	if value == nil {
-		ns.IndonesiaTimeZone, ns.Valid = "", false
+		ns.AsrMethod, ns.Valid = "", false
 		return nil
 	}
 	ns.Valid = true
-	return ns.IndonesiaTimeZone.Scan(value)
+	return ns.AsrMethod.Scan(value)
 }
 
 // Value implements the driver Valuer interface.
-func (ns NullIndonesiaTimeZone) Value() (driver.Value, error) {
+func (ns NullAsrMethod) Value() (driver.Value, error) {
 	if !ns.Valid {
 		return nil, nil
 	}
-	return string(ns.IndonesiaTimeZone), nil
+	return string(ns.AsrMethod), nil
 }
 
 type PrayerStatus string
@@ -184,6 +183,22 @@ func (ns NullTransactionStatus) Value() (driver.Value, error) {
 	return string(ns.TransactionStatus), nil
 }
 
+type ApiKey struct {
+	ID         pgtype.UUID        `json:"id"`
+	UserID     string             `json:"user_id"`
+	Name       string             `json:"name"`
+	KeyHash    string             `json:"key_hash"`
+	DailyQuota int32              `json:"daily_quota"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type CalendarFeed struct {
+	UserID       string             `json:"user_id"`
+	TokenHash    string             `json:"token_hash"`
+	IncludeTasks bool               `json:"include_tasks"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
 type Coupon struct {
 	Code               string             `json:"code"`
 	InfluencerUsername string             `json:"influencer_username"`
@@ -192,6 +207,27 @@ type Coupon struct {
 	DeletedAt          pgtype.Timestamptz `json:"deleted_at"`
 }
 
+type MessageTemplate struct {
+	Kind      string             `json:"kind"`
+	Locale    string             `json:"locale"`
+	Subject   string             `json:"subject"`
+	Body      string             `json:"body"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Notification struct {
+	ID          pgtype.UUID        `json:"id"`
+	UserID      string             `json:"user_id"`
+	TaskID      pgtype.Text        `json:"task_id"`
+	Kind        string             `json:"kind"`
+	Channel     pgtype.Text        `json:"channel"`
+	ProviderSid pgtype.Text        `json:"provider_sid"`
+	Status      string             `json:"status"`
+	Error       pgtype.Text        `json:"error"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
 type Prayer struct {
 	ID     pgtype.UUID      `json:"id"`
 	UserID string           `json:"user_id"`
@@ -202,6 +238,26 @@ type Prayer struct {
 	Day    int16            `json:"day"`
 }
 
+type PrayerCalendar struct {
+	LocationKey string             `json:"location_key"`
+	Date        pgtype.Date        `json:"date"`
+	Latitude    float64            `json:"latitude"`
+	Longitude   float64            `json:"longitude"`
+	TimeZone    string             `json:"time_zone"`
+	Provider    string             `json:"provider"`
+	Prayers     []byte             `json:"prayers"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type ReminderPreference struct {
+	UserID              string      `json:"user_id"`
+	PrayerName          string      `json:"prayer_name"`
+	Enabled             bool        `json:"enabled"`
+	LeadMinutes         int16       `json:"lead_minutes"`
+	LastReminderPercent int16       `json:"last_reminder_percent"`
+	Channel             pgtype.Text `json:"channel"`
+}
+
 type SubscriptionPlan struct {
 	ID               pgtype.UUID        `json:"id"`
 	Name             string             `json:"name"`
@@ -234,12 +290,31 @@ type Transaction struct {
 }
 
 type User struct {
-	ID            string                `json:"id"`
-	Name          string                `json:"name"`
-	Email         string                `json:"email"`
-	PhoneNumber   pgtype.Text           `json:"phone_number"`
-	PhoneVerified bool                  `json:"phone_verified"`
-	AccountType   AccountType           `json:"account_type"`
-	TimeZone      NullIndonesiaTimeZone `json:"time_zone"`
-	CreatedAt     pgtype.Timestamptz    `json:"created_at"`
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	Email                string             `json:"email"`
+	PhoneNumber          pgtype.Text        `json:"phone_number"`
+	PhoneVerified        bool               `json:"phone_verified"`
+	AccountType          AccountType        `json:"account_type"`
+	TimeZone             pgtype.Text        `json:"time_zone"`
+	City                 pgtype.Text        `json:"city"`
+	Latitude             pgtype.Float8      `json:"latitude"`
+	Longitude            pgtype.Float8      `json:"longitude"`
+	SunnahReminder       bool               `json:"sunnah_reminder"`
+	AsrMethod            AsrMethod          `json:"asr_method"`
+	PrayerOffsets        []byte             `json:"prayer_offsets"`
+	NotificationChannels []byte             `json:"notification_channels"`
+	QuietWindows         []byte             `json:"quiet_windows"`
+	RemindersPausedUntil pgtype.Timestamptz `json:"reminders_paused_until"`
+	Locale               string             `json:"locale"`
+	LocationKey          pgtype.Text        `json:"location_key"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+}
+
+type UserDevice struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    string             `json:"user_id"`
+	Token     string             `json:"token"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
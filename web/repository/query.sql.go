@@ -20,6 +20,81 @@ type CreatePrayersParams struct {
 	Day    int16       `json:"day"`
 }
 
+const countAPIKeysByUserID = `-- name: CountAPIKeysByUserID :one
+SELECT COUNT(*) FROM api_key ak WHERE ak.user_id = $1
+`
+
+func (q *Queries) CountAPIKeysByUserID(ctx context.Context, userID string) (int64, error) {
+	row := q.db.QueryRow(ctx, countAPIKeysByUserID, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_key (user_id, name, key_hash, daily_quota) VALUES ($1, $2, $3, $4)
+RETURNING id, name, daily_quota, created_at
+`
+
+type CreateAPIKeyParams struct {
+	UserID     string `json:"user_id"`
+	Name       string `json:"name"`
+	KeyHash    string `json:"key_hash"`
+	DailyQuota int32  `json:"daily_quota"`
+}
+
+type CreateAPIKeyRow struct {
+	ID         pgtype.UUID        `json:"id"`
+	Name       string             `json:"name"`
+	DailyQuota int32              `json:"daily_quota"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (CreateAPIKeyRow, error) {
+	row := q.db.QueryRow(ctx, createAPIKey,
+		arg.UserID,
+		arg.Name,
+		arg.KeyHash,
+		arg.DailyQuota,
+	)
+	var i CreateAPIKeyRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DailyQuota,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createNotification = `-- name: CreateNotification :exec
+INSERT INTO notification (user_id, task_id, kind, channel, provider_sid, status, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateNotificationParams struct {
+	UserID      string      `json:"user_id"`
+	TaskID      pgtype.Text `json:"task_id"`
+	Kind        string      `json:"kind"`
+	Channel     pgtype.Text `json:"channel"`
+	ProviderSid pgtype.Text `json:"provider_sid"`
+	Status      string      `json:"status"`
+	Error       pgtype.Text `json:"error"`
+}
+
+func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) error {
+	_, err := q.db.Exec(ctx, createNotification,
+		arg.UserID,
+		arg.TaskID,
+		arg.Kind,
+		arg.Channel,
+		arg.ProviderSid,
+		arg.Status,
+		arg.Error,
+	)
+	return err
+}
+
 const createTask = `-- name: CreateTask :one
 INSERT INTO task (user_id, name, description) VALUES ($1, $2, $3) RETURNING id, name, description, checked
 `
@@ -80,7 +155,7 @@ func (q *Queries) CreateTx(ctx context.Context, arg CreateTxParams) error {
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO "user" (id, name, email) VALUES ($1, $2, $3) RETURNING id, name, email, phone_number, phone_verified, account_type, time_zone, created_at
+INSERT INTO "user" (id, name, email) VALUES ($1, $2, $3) RETURNING id, name, email, phone_number, phone_verified, account_type, time_zone, city, latitude, longitude, sunnah_reminder, asr_method, prayer_offsets, notification_channels, quiet_windows, reminders_paused_until, locale, location_key, created_at
 `
 
 type CreateUserParams struct {
@@ -100,6 +175,17 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PhoneVerified,
 		&i.AccountType,
 		&i.TimeZone,
+		&i.City,
+		&i.Latitude,
+		&i.Longitude,
+		&i.SunnahReminder,
+		&i.AsrMethod,
+		&i.PrayerOffsets,
+		&i.NotificationChannels,
+		&i.QuietWindows,
+		&i.RemindersPausedUntil,
+		&i.Locale,
+		&i.LocationKey,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -117,6 +203,32 @@ func (q *Queries) DecrementCouponQuota(ctx context.Context, code string) (int16,
 	return quota, err
 }
 
+const deleteAPIKey = `-- name: DeleteAPIKey :execrows
+DELETE FROM api_key WHERE id = $1 AND user_id = $2
+`
+
+type DeleteAPIKeyParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID string      `json:"user_id"`
+}
+
+func (q *Queries) DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAPIKey, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCalendarFeed = `-- name: DeleteCalendarFeed :exec
+DELETE FROM calendar_feed WHERE user_id = $1
+`
+
+func (q *Queries) DeleteCalendarFeed(ctx context.Context, userID string) error {
+	_, err := q.db.Exec(ctx, deleteCalendarFeed, userID)
+	return err
+}
+
 const deleteTaskByID = `-- name: DeleteTaskByID :exec
 DELETE FROM task WHERE id = $1
 `
@@ -136,6 +248,286 @@ func (q *Queries) DeleteUserByID(ctx context.Context, id string) (string, error)
 	return id, err
 }
 
+const deleteUserDevice = `-- name: DeleteUserDevice :execrows
+DELETE FROM user_device WHERE user_id = $1 AND token = $2
+`
+
+type DeleteUserDeviceParams struct {
+	UserID string `json:"user_id"`
+	Token  string `json:"token"`
+}
+
+func (q *Queries) DeleteUserDevice(ctx context.Context, arg DeleteUserDeviceParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserDevice, arg.UserID, arg.Token)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
+SELECT ak.id, ak.daily_quota FROM api_key ak WHERE ak.key_hash = $1
+`
+
+type GetAPIKeyByHashRow struct {
+	ID         pgtype.UUID `json:"id"`
+	DailyQuota int32       `json:"daily_quota"`
+}
+
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (GetAPIKeyByHashRow, error) {
+	row := q.db.QueryRow(ctx, getAPIKeyByHash, keyHash)
+	var i GetAPIKeyByHashRow
+	err := row.Scan(&i.ID, &i.DailyQuota)
+	return i, err
+}
+
+const getAPIKeysByUserID = `-- name: GetAPIKeysByUserID :many
+SELECT
+  ak.id,
+  ak.name,
+  ak.daily_quota,
+  ak.created_at
+FROM api_key ak WHERE ak.user_id = $1 ORDER BY ak.created_at
+`
+
+type GetAPIKeysByUserIDRow struct {
+	ID         pgtype.UUID        `json:"id"`
+	Name       string             `json:"name"`
+	DailyQuota int32              `json:"daily_quota"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) GetAPIKeysByUserID(ctx context.Context, userID string) ([]GetAPIKeysByUserIDRow, error) {
+	rows, err := q.db.Query(ctx, getAPIKeysByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAPIKeysByUserIDRow
+	for rows.Next() {
+		var i GetAPIKeysByUserIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.DailyQuota,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCalendarFeedByTokenHash = `-- name: GetCalendarFeedByTokenHash :one
+SELECT cf.user_id, cf.include_tasks FROM calendar_feed cf WHERE cf.token_hash = $1
+`
+
+type GetCalendarFeedByTokenHashRow struct {
+	UserID       string `json:"user_id"`
+	IncludeTasks bool   `json:"include_tasks"`
+}
+
+func (q *Queries) GetCalendarFeedByTokenHash(ctx context.Context, tokenHash string) (GetCalendarFeedByTokenHashRow, error) {
+	row := q.db.QueryRow(ctx, getCalendarFeedByTokenHash, tokenHash)
+	var i GetCalendarFeedByTokenHashRow
+	err := row.Scan(&i.UserID, &i.IncludeTasks)
+	return i, err
+}
+
+const getMessageTemplate = `-- name: GetMessageTemplate :one
+SELECT mt.subject, mt.body FROM message_template mt WHERE mt.kind = $1 AND mt.locale = $2
+`
+
+type GetMessageTemplateParams struct {
+	Kind   string `json:"kind"`
+	Locale string `json:"locale"`
+}
+
+type GetMessageTemplateRow struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+func (q *Queries) GetMessageTemplate(ctx context.Context, arg GetMessageTemplateParams) (GetMessageTemplateRow, error) {
+	row := q.db.QueryRow(ctx, getMessageTemplate, arg.Kind, arg.Locale)
+	var i GetMessageTemplateRow
+	err := row.Scan(&i.Subject, &i.Body)
+	return i, err
+}
+
+const getNotificationsByUserID = `-- name: GetNotificationsByUserID :many
+SELECT
+  n.id,
+  n.kind,
+  n.channel,
+  n.status,
+  n.error,
+  n.created_at,
+  n.updated_at
+FROM notification n WHERE n.user_id = $1 AND n.created_at < $2
+ORDER BY n.created_at DESC LIMIT $3
+`
+
+type GetNotificationsByUserIDParams struct {
+	UserID    string             `json:"user_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Limit     int32              `json:"limit"`
+}
+
+type GetNotificationsByUserIDRow struct {
+	ID        pgtype.UUID        `json:"id"`
+	Kind      string             `json:"kind"`
+	Channel   pgtype.Text        `json:"channel"`
+	Status    string             `json:"status"`
+	Error     pgtype.Text        `json:"error"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) GetNotificationsByUserID(ctx context.Context, arg GetNotificationsByUserIDParams) ([]GetNotificationsByUserIDRow, error) {
+	rows, err := q.db.Query(ctx, getNotificationsByUserID, arg.UserID, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetNotificationsByUserIDRow
+	for rows.Next() {
+		var i GetNotificationsByUserIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.Channel,
+			&i.Status,
+			&i.Error,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrayerByUserIDAndDate = `-- name: GetPrayerByUserIDAndDate :one
+SELECT p.id, p.status FROM prayer p
+WHERE p.user_id = $1 AND p.name = $2 AND p.year = $3 AND p.month = $4 AND p.day = $5
+`
+
+type GetPrayerByUserIDAndDateParams struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Year   int16  `json:"year"`
+	Month  int16  `json:"month"`
+	Day    int16  `json:"day"`
+}
+
+type GetPrayerByUserIDAndDateRow struct {
+	ID     pgtype.UUID      `json:"id"`
+	Status NullPrayerStatus `json:"status"`
+}
+
+func (q *Queries) GetPrayerByUserIDAndDate(ctx context.Context, arg GetPrayerByUserIDAndDateParams) (GetPrayerByUserIDAndDateRow, error) {
+	row := q.db.QueryRow(ctx, getPrayerByUserIDAndDate,
+		arg.UserID,
+		arg.Name,
+		arg.Year,
+		arg.Month,
+		arg.Day,
+	)
+	var i GetPrayerByUserIDAndDateRow
+	err := row.Scan(&i.ID, &i.Status)
+	return i, err
+}
+
+const getPrayerCalendar = `-- name: GetPrayerCalendar :many
+SELECT pc.date, pc.prayers FROM prayer_calendar pc
+WHERE pc.location_key = $1 AND pc.date BETWEEN $2 AND $3
+ORDER BY pc.date
+`
+
+type GetPrayerCalendarParams struct {
+	LocationKey string      `json:"location_key"`
+	FromDate    pgtype.Date `json:"from_date"`
+	ToDate      pgtype.Date `json:"to_date"`
+}
+
+type GetPrayerCalendarRow struct {
+	Date    pgtype.Date `json:"date"`
+	Prayers []byte      `json:"prayers"`
+}
+
+func (q *Queries) GetPrayerCalendar(ctx context.Context, arg GetPrayerCalendarParams) ([]GetPrayerCalendarRow, error) {
+	rows, err := q.db.Query(ctx, getPrayerCalendar, arg.LocationKey, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrayerCalendarRow
+	for rows.Next() {
+		var i GetPrayerCalendarRow
+		if err := rows.Scan(&i.Date, &i.Prayers); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReminderPreferences = `-- name: GetReminderPreferences :many
+SELECT
+  rp.prayer_name,
+  rp.enabled,
+  rp.lead_minutes,
+  rp.last_reminder_percent,
+  rp.channel
+FROM reminder_preference rp WHERE rp.user_id = $1
+`
+
+type GetReminderPreferencesRow struct {
+	PrayerName          string      `json:"prayer_name"`
+	Enabled             bool        `json:"enabled"`
+	LeadMinutes         int16       `json:"lead_minutes"`
+	LastReminderPercent int16       `json:"last_reminder_percent"`
+	Channel             pgtype.Text `json:"channel"`
+}
+
+func (q *Queries) GetReminderPreferences(ctx context.Context, userID string) ([]GetReminderPreferencesRow, error) {
+	rows, err := q.db.Query(ctx, getReminderPreferences, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReminderPreferencesRow
+	for rows.Next() {
+		var i GetReminderPreferencesRow
+		if err := rows.Scan(
+			&i.PrayerName,
+			&i.Enabled,
+			&i.LeadMinutes,
+			&i.LastReminderPercent,
+			&i.Channel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getSubsPlans = `-- name: GetSubsPlans :many
 SELECT id, name, price, duration_in_months, created_at, deleted_at FROM subscription_plan WHERE deleted_at IS NULL
 `
@@ -212,7 +604,8 @@ const getThisMonthPrayers = `-- name: GetThisMonthPrayers :many
 SELECT
   p.id,
   p.name,
-  p.status
+  p.status,
+  p.day
 FROM prayer p WHERE p.user_id = $1 AND p.year = $2 AND p.month = $3 AND p.status IS NOT NULL
 `
 
@@ -226,6 +619,7 @@ type GetThisMonthPrayersRow struct {
 	ID     pgtype.UUID      `json:"id"`
 	Name   string           `json:"name"`
 	Status NullPrayerStatus `json:"status"`
+	Day    int16            `json:"day"`
 }
 
 func (q *Queries) GetThisMonthPrayers(ctx context.Context, arg GetThisMonthPrayersParams) ([]GetThisMonthPrayersRow, error) {
@@ -237,7 +631,12 @@ func (q *Queries) GetThisMonthPrayers(ctx context.Context, arg GetThisMonthPraye
 	var items []GetThisMonthPrayersRow
 	for rows.Next() {
 		var i GetThisMonthPrayersRow
-		if err := rows.Scan(&i.ID, &i.Name, &i.Status); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Status,
+			&i.Day,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -393,7 +792,7 @@ func (q *Queries) GetTxWithSubsPlanByID(ctx context.Context, id pgtype.UUID) (Ge
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, name, email, phone_number, phone_verified, account_type, time_zone, created_at FROM "user" WHERE id = $1
+SELECT id, name, email, phone_number, phone_verified, account_type, time_zone, city, latitude, longitude, sunnah_reminder, asr_method, prayer_offsets, notification_channels, quiet_windows, reminders_paused_until, locale, location_key, created_at FROM "user" WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id string) (User, error) {
@@ -407,13 +806,24 @@ func (q *Queries) GetUserByID(ctx context.Context, id string) (User, error) {
 		&i.PhoneVerified,
 		&i.AccountType,
 		&i.TimeZone,
+		&i.City,
+		&i.Latitude,
+		&i.Longitude,
+		&i.SunnahReminder,
+		&i.AsrMethod,
+		&i.PrayerOffsets,
+		&i.NotificationChannels,
+		&i.QuietWindows,
+		&i.RemindersPausedUntil,
+		&i.Locale,
+		&i.LocationKey,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getUserByPhoneNumber = `-- name: GetUserByPhoneNumber :one
-SELECT id, name, email, phone_number, phone_verified, account_type, time_zone, created_at FROM "user" WHERE phone_number = $1
+SELECT id, name, email, phone_number, phone_verified, account_type, time_zone, city, latitude, longitude, sunnah_reminder, asr_method, prayer_offsets, notification_channels, quiet_windows, reminders_paused_until, locale, location_key, created_at FROM "user" WHERE phone_number = $1
 `
 
 func (q *Queries) GetUserByPhoneNumber(ctx context.Context, phoneNumber pgtype.Text) (User, error) {
@@ -427,11 +837,78 @@ func (q *Queries) GetUserByPhoneNumber(ctx context.Context, phoneNumber pgtype.T
 		&i.PhoneVerified,
 		&i.AccountType,
 		&i.TimeZone,
+		&i.City,
+		&i.Latitude,
+		&i.Longitude,
+		&i.SunnahReminder,
+		&i.AsrMethod,
+		&i.PrayerOffsets,
+		&i.NotificationChannels,
+		&i.QuietWindows,
+		&i.RemindersPausedUntil,
+		&i.Locale,
+		&i.LocationKey,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const getUserLocaleByID = `-- name: GetUserLocaleByID :one
+SELECT u.locale FROM "user" u WHERE u.id = $1
+`
+
+func (q *Queries) GetUserLocaleByID(ctx context.Context, id string) (string, error) {
+	row := q.db.QueryRow(ctx, getUserLocaleByID, id)
+	var locale string
+	err := row.Scan(&locale)
+	return locale, err
+}
+
+const getUserLocationByID = `-- name: GetUserLocationByID :one
+SELECT
+  u.city,
+  u.latitude,
+  u.longitude,
+  u.time_zone,
+  u.asr_method,
+  u.prayer_offsets
+FROM "user" u WHERE u.id = $1
+`
+
+type GetUserLocationByIDRow struct {
+	City          pgtype.Text   `json:"city"`
+	Latitude      pgtype.Float8 `json:"latitude"`
+	Longitude     pgtype.Float8 `json:"longitude"`
+	TimeZone      pgtype.Text   `json:"time_zone"`
+	AsrMethod     AsrMethod     `json:"asr_method"`
+	PrayerOffsets []byte        `json:"prayer_offsets"`
+}
+
+func (q *Queries) GetUserLocationByID(ctx context.Context, id string) (GetUserLocationByIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserLocationByID, id)
+	var i GetUserLocationByIDRow
+	err := row.Scan(
+		&i.City,
+		&i.Latitude,
+		&i.Longitude,
+		&i.TimeZone,
+		&i.AsrMethod,
+		&i.PrayerOffsets,
+	)
+	return i, err
+}
+
+const getUserRemindersPausedUntilByID = `-- name: GetUserRemindersPausedUntilByID :one
+SELECT u.reminders_paused_until FROM "user" u WHERE u.id = $1
+`
+
+func (q *Queries) GetUserRemindersPausedUntilByID(ctx context.Context, id string) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getUserRemindersPausedUntilByID, id)
+	var reminders_paused_until pgtype.Timestamptz
+	err := row.Scan(&reminders_paused_until)
+	return reminders_paused_until, err
+}
+
 const getUserSubsByID = `-- name: GetUserSubsByID :one
 SELECT u.account_type FROM "user" u WHERE u.id = $1
 `
@@ -447,9 +924,9 @@ const getUserTimeZoneByID = `-- name: GetUserTimeZoneByID :one
 SELECT u.time_zone FROM "user" u WHERE u.id = $1
 `
 
-func (q *Queries) GetUserTimeZoneByID(ctx context.Context, id string) (NullIndonesiaTimeZone, error) {
+func (q *Queries) GetUserTimeZoneByID(ctx context.Context, id string) (pgtype.Text, error) {
 	row := q.db.QueryRow(ctx, getUserTimeZoneByID, id)
-	var time_zone NullIndonesiaTimeZone
+	var time_zone pgtype.Text
 	err := row.Scan(&time_zone)
 	return time_zone, err
 }
@@ -463,6 +940,48 @@ func (q *Queries) IncrementCouponQuota(ctx context.Context, code string) error {
 	return err
 }
 
+const updateCalendarFeedIncludeTasks = `-- name: UpdateCalendarFeedIncludeTasks :execrows
+UPDATE calendar_feed SET include_tasks = $2 WHERE user_id = $1
+`
+
+type UpdateCalendarFeedIncludeTasksParams struct {
+	UserID       string `json:"user_id"`
+	IncludeTasks bool   `json:"include_tasks"`
+}
+
+func (q *Queries) UpdateCalendarFeedIncludeTasks(ctx context.Context, arg UpdateCalendarFeedIncludeTasksParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateCalendarFeedIncludeTasks, arg.UserID, arg.IncludeTasks)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateNotificationStatus = `-- name: UpdateNotificationStatus :execrows
+UPDATE notification SET status = $1, error = $2, updated_at = CURRENT_TIMESTAMP
+WHERE provider_sid = $3 AND status = ANY($4::VARCHAR[])
+`
+
+type UpdateNotificationStatusParams struct {
+	Status       string      `json:"status"`
+	Error        pgtype.Text `json:"error"`
+	ProviderSid  pgtype.Text `json:"provider_sid"`
+	FromStatuses []string    `json:"from_statuses"`
+}
+
+func (q *Queries) UpdateNotificationStatus(ctx context.Context, arg UpdateNotificationStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateNotificationStatus,
+		arg.Status,
+		arg.Error,
+		arg.ProviderSid,
+		arg.FromStatuses,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updatePrayerStatus = `-- name: UpdatePrayerStatus :exec
 UPDATE prayer SET status = $2 WHERE id = $1
 `
@@ -513,6 +1032,57 @@ func (q *Queries) UpdateTxStatus(ctx context.Context, arg UpdateTxStatusParams)
 	return err
 }
 
+const updateUserLocale = `-- name: UpdateUserLocale :exec
+UPDATE "user" SET locale = $2 WHERE id = $1
+`
+
+type UpdateUserLocaleParams struct {
+	ID     string `json:"id"`
+	Locale string `json:"locale"`
+}
+
+func (q *Queries) UpdateUserLocale(ctx context.Context, arg UpdateUserLocaleParams) error {
+	_, err := q.db.Exec(ctx, updateUserLocale, arg.ID, arg.Locale)
+	return err
+}
+
+const updateUserLocation = `-- name: UpdateUserLocation :exec
+UPDATE "user" SET city = $2, latitude = $3, longitude = $4, location_key = $5 WHERE id = $1
+`
+
+type UpdateUserLocationParams struct {
+	ID          string        `json:"id"`
+	City        pgtype.Text   `json:"city"`
+	Latitude    pgtype.Float8 `json:"latitude"`
+	Longitude   pgtype.Float8 `json:"longitude"`
+	LocationKey pgtype.Text   `json:"location_key"`
+}
+
+func (q *Queries) UpdateUserLocation(ctx context.Context, arg UpdateUserLocationParams) error {
+	_, err := q.db.Exec(ctx, updateUserLocation,
+		arg.ID,
+		arg.City,
+		arg.Latitude,
+		arg.Longitude,
+		arg.LocationKey,
+	)
+	return err
+}
+
+const updateUserNotificationChannels = `-- name: UpdateUserNotificationChannels :exec
+UPDATE "user" SET notification_channels = $2 WHERE id = $1
+`
+
+type UpdateUserNotificationChannelsParams struct {
+	ID                   string `json:"id"`
+	NotificationChannels []byte `json:"notification_channels"`
+}
+
+func (q *Queries) UpdateUserNotificationChannels(ctx context.Context, arg UpdateUserNotificationChannelsParams) error {
+	_, err := q.db.Exec(ctx, updateUserNotificationChannels, arg.ID, arg.NotificationChannels)
+	return err
+}
+
 const updateUserPhoneNumber = `-- name: UpdateUserPhoneNumber :exec
 UPDATE "user" SET phone_number = $2, phone_verified = $3 WHERE id = $1
 `
@@ -528,6 +1098,49 @@ func (q *Queries) UpdateUserPhoneNumber(ctx context.Context, arg UpdateUserPhone
 	return err
 }
 
+const updateUserPrayerSettings = `-- name: UpdateUserPrayerSettings :exec
+UPDATE "user" SET asr_method = $2, prayer_offsets = $3 WHERE id = $1
+`
+
+type UpdateUserPrayerSettingsParams struct {
+	ID            string    `json:"id"`
+	AsrMethod     AsrMethod `json:"asr_method"`
+	PrayerOffsets []byte    `json:"prayer_offsets"`
+}
+
+func (q *Queries) UpdateUserPrayerSettings(ctx context.Context, arg UpdateUserPrayerSettingsParams) error {
+	_, err := q.db.Exec(ctx, updateUserPrayerSettings, arg.ID, arg.AsrMethod, arg.PrayerOffsets)
+	return err
+}
+
+const updateUserQuietWindows = `-- name: UpdateUserQuietWindows :exec
+UPDATE "user" SET quiet_windows = $2 WHERE id = $1
+`
+
+type UpdateUserQuietWindowsParams struct {
+	ID           string `json:"id"`
+	QuietWindows []byte `json:"quiet_windows"`
+}
+
+func (q *Queries) UpdateUserQuietWindows(ctx context.Context, arg UpdateUserQuietWindowsParams) error {
+	_, err := q.db.Exec(ctx, updateUserQuietWindows, arg.ID, arg.QuietWindows)
+	return err
+}
+
+const updateUserRemindersPausedUntil = `-- name: UpdateUserRemindersPausedUntil :exec
+UPDATE "user" SET reminders_paused_until = $2 WHERE id = $1
+`
+
+type UpdateUserRemindersPausedUntilParams struct {
+	ID                   string             `json:"id"`
+	RemindersPausedUntil pgtype.Timestamptz `json:"reminders_paused_until"`
+}
+
+func (q *Queries) UpdateUserRemindersPausedUntil(ctx context.Context, arg UpdateUserRemindersPausedUntilParams) error {
+	_, err := q.db.Exec(ctx, updateUserRemindersPausedUntil, arg.ID, arg.RemindersPausedUntil)
+	return err
+}
+
 const updateUserSubs = `-- name: UpdateUserSubs :exec
 UPDATE "user" SET account_type = $2 WHERE id = $1
 `
@@ -542,16 +1155,93 @@ func (q *Queries) UpdateUserSubs(ctx context.Context, arg UpdateUserSubsParams)
 	return err
 }
 
+const updateUserSunnahReminder = `-- name: UpdateUserSunnahReminder :exec
+UPDATE "user" SET sunnah_reminder = $2 WHERE id = $1
+`
+
+type UpdateUserSunnahReminderParams struct {
+	ID             string `json:"id"`
+	SunnahReminder bool   `json:"sunnah_reminder"`
+}
+
+func (q *Queries) UpdateUserSunnahReminder(ctx context.Context, arg UpdateUserSunnahReminderParams) error {
+	_, err := q.db.Exec(ctx, updateUserSunnahReminder, arg.ID, arg.SunnahReminder)
+	return err
+}
+
 const updateUserTimeZone = `-- name: UpdateUserTimeZone :exec
-UPDATE "user" SET time_zone = $2 WHERE id = $1
+UPDATE "user" SET time_zone = $2, location_key = $3 WHERE id = $1
 `
 
 type UpdateUserTimeZoneParams struct {
-	ID       string                `json:"id"`
-	TimeZone NullIndonesiaTimeZone `json:"time_zone"`
+	ID          string      `json:"id"`
+	TimeZone    pgtype.Text `json:"time_zone"`
+	LocationKey pgtype.Text `json:"location_key"`
 }
 
 func (q *Queries) UpdateUserTimeZone(ctx context.Context, arg UpdateUserTimeZoneParams) error {
-	_, err := q.db.Exec(ctx, updateUserTimeZone, arg.ID, arg.TimeZone)
+	_, err := q.db.Exec(ctx, updateUserTimeZone, arg.ID, arg.TimeZone, arg.LocationKey)
+	return err
+}
+
+const upsertCalendarFeed = `-- name: UpsertCalendarFeed :exec
+INSERT INTO calendar_feed (user_id, token_hash, include_tasks) VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, include_tasks = EXCLUDED.include_tasks, created_at = CURRENT_TIMESTAMP
+`
+
+type UpsertCalendarFeedParams struct {
+	UserID       string `json:"user_id"`
+	TokenHash    string `json:"token_hash"`
+	IncludeTasks bool   `json:"include_tasks"`
+}
+
+func (q *Queries) UpsertCalendarFeed(ctx context.Context, arg UpsertCalendarFeedParams) error {
+	_, err := q.db.Exec(ctx, upsertCalendarFeed, arg.UserID, arg.TokenHash, arg.IncludeTasks)
+	return err
+}
+
+const upsertReminderPreference = `-- name: UpsertReminderPreference :exec
+INSERT INTO reminder_preference (user_id, prayer_name, enabled, lead_minutes, last_reminder_percent, channel)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, prayer_name) DO UPDATE SET
+  enabled = EXCLUDED.enabled,
+  lead_minutes = EXCLUDED.lead_minutes,
+  last_reminder_percent = EXCLUDED.last_reminder_percent,
+  channel = EXCLUDED.channel
+`
+
+type UpsertReminderPreferenceParams struct {
+	UserID              string      `json:"user_id"`
+	PrayerName          string      `json:"prayer_name"`
+	Enabled             bool        `json:"enabled"`
+	LeadMinutes         int16       `json:"lead_minutes"`
+	LastReminderPercent int16       `json:"last_reminder_percent"`
+	Channel             pgtype.Text `json:"channel"`
+}
+
+func (q *Queries) UpsertReminderPreference(ctx context.Context, arg UpsertReminderPreferenceParams) error {
+	_, err := q.db.Exec(ctx, upsertReminderPreference,
+		arg.UserID,
+		arg.PrayerName,
+		arg.Enabled,
+		arg.LeadMinutes,
+		arg.LastReminderPercent,
+		arg.Channel,
+	)
+	return err
+}
+
+const upsertUserDevice = `-- name: UpsertUserDevice :exec
+INSERT INTO user_device (user_id, token) VALUES ($1, $2)
+ON CONFLICT (token) DO UPDATE SET user_id = EXCLUDED.user_id, updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertUserDeviceParams struct {
+	UserID string `json:"user_id"`
+	Token  string `json:"token"`
+}
+
+func (q *Queries) UpsertUserDevice(ctx context.Context, arg UpsertUserDeviceParams) error {
+	_, err := q.db.Exec(ctx, upsertUserDevice, arg.UserID, arg.Token)
 	return err
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/mdayat/demi-masa/worker/internal/simulation"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	zerolog.CallerMarshalFunc = func(pc uintptr, file string, line int) string {
+		return filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+
+	from := flag.String("from", "2026-12-20", "date the simulation starts at, in UTC")
+	days := flag.Int("days", 45, "number of days to simulate")
+	verbose := flag.Bool("verbose", false, "log every task")
+	flag.Parse()
+
+	logger := log.With().Caller().Logger()
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	if *verbose {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+
+	start, err := time.Parse(time.DateOnly, *from)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	ctx := logger.WithContext(context.TODO())
+	report, err := simulation.Run(ctx, simulation.Config{
+		Start: start,
+		End:   start.AddDate(0, 0, *days),
+		Users: simulation.DefaultUsers,
+	})
+
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	fmt.Printf(
+		"%d tasks, %d retries, %d notifications, %d prayers checked\n",
+		report.Tasks,
+		report.Retries,
+		report.Notifications,
+		report.Prayers,
+	)
+
+	for _, violation := range report.Violations {
+		fmt.Println(violation)
+	}
+
+	if len(report.Violations) != 0 {
+		os.Exit(1)
+	}
+}
@@ -7,11 +7,21 @@ import (
 )
 
 var (
-	DATABASE_URL       string
-	TWILIO_ACCOUNT_SID string
-	TWILIO_AUTH_TOKEN  string
-	REDIS_URL          string
-	TWILIO_SENDER      string
+	DATABASE_URL               string
+	TWILIO_ACCOUNT_SID         string
+	TWILIO_AUTH_TOKEN          string
+	REDIS_URL                  string
+	TWILIO_SENDER              string
+	PRAYER_PROVIDERS           string
+	HIJRI_OFFSETS              string
+	NOTIFICATION_CHANNELS      string
+	TWILIO_SMS_SENDER          string
+	SMTP_HOST                  string
+	SMTP_PORT                  string
+	SMTP_USERNAME              string
+	SMTP_PASSWORD              string
+	SMTP_SENDER                string
+	TWILIO_STATUS_CALLBACK_URL string
 )
 
 func Init() error {
@@ -25,6 +35,16 @@ func Init() error {
 	TWILIO_AUTH_TOKEN = os.Getenv("TWILIO_AUTH_TOKEN")
 	REDIS_URL = os.Getenv("REDIS_URL")
 	TWILIO_SENDER = os.Getenv("TWILIO_SENDER")
+	PRAYER_PROVIDERS = os.Getenv("PRAYER_PROVIDERS")
+	HIJRI_OFFSETS = os.Getenv("HIJRI_OFFSETS")
+	NOTIFICATION_CHANNELS = os.Getenv("NOTIFICATION_CHANNELS")
+	TWILIO_SMS_SENDER = os.Getenv("TWILIO_SMS_SENDER")
+	SMTP_HOST = os.Getenv("SMTP_HOST")
+	SMTP_PORT = os.Getenv("SMTP_PORT")
+	SMTP_USERNAME = os.Getenv("SMTP_USERNAME")
+	SMTP_PASSWORD = os.Getenv("SMTP_PASSWORD")
+	SMTP_SENDER = os.Getenv("SMTP_SENDER")
+	TWILIO_STATUS_CALLBACK_URL = os.Getenv("TWILIO_STATUS_CALLBACK_URL")
 
 	return nil
 }
@@ -4,13 +4,29 @@ import (
 	"github.com/hibiken/asynq"
 )
 
+// TaskClient and TaskInspector are the parts of the asynq client and
+// inspector the worker uses, a simulation swaps them for a queue running on
+// a virtual clock.
+type TaskClient interface {
+	Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
+type TaskInspector interface {
+	GetTaskInfo(queue, id string) (*asynq.TaskInfo, error)
+	ListScheduledTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+	ListArchivedTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+	RunTask(queue, id string) error
+	DeleteTask(queue, id string) error
+}
+
 var (
-	AsynqClient    *asynq.Client
-	AsynqInspector *asynq.Inspector
+	AsynqClient    TaskClient
+	AsynqInspector TaskInspector
 )
 
 func InitAsynq(redisURL string) (*asynq.Client, *asynq.Inspector) {
-	AsynqClient = asynq.NewClient(asynq.RedisClientOpt{Addr: redisURL})
-	AsynqInspector = asynq.NewInspector(asynq.RedisClientOpt{Addr: redisURL})
-	return AsynqClient, AsynqInspector
+	asynqClient := asynq.NewClient(asynq.RedisClientOpt{Addr: redisURL})
+	asynqInspector := asynq.NewInspector(asynq.RedisClientOpt{Addr: redisURL})
+	AsynqClient, AsynqInspector = asynqClient, asynqInspector
+	return asynqClient, asynqInspector
 }
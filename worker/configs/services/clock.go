@@ -0,0 +1,9 @@
+package services
+
+import (
+	"github.com/mdayat/demi-masa/pkg/clock"
+)
+
+var (
+	Clock clock.Clock = clock.System
+)
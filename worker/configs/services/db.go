@@ -9,7 +9,7 @@ import (
 
 var (
 	DB      *pgxpool.Pool
-	Queries *repository.Queries
+	Queries repository.Querier
 )
 
 func InitDB(ctx context.Context, dbURL string) (*pgxpool.Pool, error) {
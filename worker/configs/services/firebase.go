@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+	"github.com/mdayat/demi-masa/pkg/notifier"
+	"github.com/pkg/errors"
+)
+
+var (
+	FirebaseApp       *firebase.App
+	FirebaseMessaging *messaging.Client
+	PushClient        notifier.PushClient
+)
+
+func InitFirebase(ctx context.Context) error {
+	var err error
+	FirebaseApp, err = firebase.NewApp(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	FirebaseMessaging, err = FirebaseApp.Messaging(ctx)
+	if err != nil {
+		return err
+	}
+
+	PushClient = fcmClient{}
+	return nil
+}
+
+type fcmClient struct{}
+
+func (fcmClient) Send(ctx context.Context, tokens []string, message notifier.Message) ([]string, error) {
+	resp, err := FirebaseMessaging.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+		Tokens: tokens,
+		Notification: &messaging.Notification{
+			Title: message.Subject,
+			Body:  message.Body,
+		},
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send multicast message")
+	}
+
+	invalidTokens := make([]string, 0, resp.FailureCount)
+	for i, v := range resp.Responses {
+		if v.Success == false && (messaging.IsUnregistered(v.Error) || messaging.IsInvalidArgument(v.Error)) {
+			invalidTokens = append(invalidTokens, tokens[i])
+		}
+	}
+
+	if resp.SuccessCount == 0 {
+		return invalidTokens, errors.New(fmt.Sprintf("all %d push messages failed", resp.FailureCount))
+	}
+	return invalidTokens, nil
+}
+
+// userDeviceStore is the device registry kept in postgres, the web service
+// registers the tokens and the worker prunes the invalid ones.
+type userDeviceStore struct{}
+
+var UserDeviceStore notifier.DeviceStore = userDeviceStore{}
+
+func (userDeviceStore) GetDeviceTokens(ctx context.Context, userID string) ([]string, error) {
+	return Queries.GetUserDeviceTokens(ctx, userID)
+}
+
+func (userDeviceStore) DeleteDeviceTokens(ctx context.Context, tokens []string) error {
+	return Queries.DeleteUserDevicesByTokens(ctx, tokens)
+}
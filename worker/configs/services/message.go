@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/worker/repository"
+	"github.com/pkg/errors"
+)
+
+// Edited templates are picked up once the cached copy expires.
+const messageTemplateCacheTTL = 5 * time.Minute
+
+var (
+	MessageStore *message.Store
+)
+
+func InitMessageStore() error {
+	var err error
+	MessageStore, err = message.NewStore(messageTemplateRepository{}, messageTemplateCacheTTL, Clock)
+	if err != nil {
+		return errors.Wrap(err, "failed to create message store")
+	}
+	return nil
+}
+
+type messageTemplateRepository struct{}
+
+func (messageTemplateRepository) GetTemplate(ctx context.Context, kind message.Kind, locale message.Locale) (message.Template, error) {
+	row, err := Queries.GetMessageTemplate(ctx, repository.GetMessageTemplateParams{
+		Kind:   string(kind),
+		Locale: string(locale),
+	})
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return message.Template{}, message.ErrTemplateNotFound
+		}
+		return message.Template{}, errors.Wrap(err, "failed to get message template")
+	}
+
+	return message.Template{Subject: row.Subject, Body: row.Body}, nil
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"github.com/mdayat/demi-masa/pkg/notifier"
+	"github.com/pkg/errors"
+)
+
+var (
+	Notifier notifier.Dispatcher
+)
+
+func InitNotifier(config notifier.Config) error {
+	var err error
+	Notifier, err = notifier.NewDispatcherFromConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create notifier")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/worker/repository"
+	"github.com/pkg/errors"
+)
+
+var (
+	PrayerProviders []prayer.Provider
+)
+
+func InitPrayerProviders(providerNames string) error {
+	if providerNames == "" {
+		providerNames = strings.Join([]string{prayer.AladhanProviderName, prayer.CalculatorProviderName}, ",")
+	}
+
+	PrayerProviders = nil
+	for _, name := range strings.Split(providerNames, ",") {
+		provider, err := prayer.NewProvider(strings.TrimSpace(name))
+		if err != nil {
+			return errors.Wrap(err, "failed to create prayer time provider")
+		}
+		PrayerProviders = append(PrayerProviders, provider)
+	}
+
+	return nil
+}
+
+var (
+	HijriCalendar prayer.HijriCalendar
+)
+
+func InitHijriCalendar(hijriOffsets string) error {
+	offsets, err := prayer.ParseHijriOffsets(hijriOffsets)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse hijri offsets")
+	}
+
+	HijriCalendar = prayer.NewHijriCalendar(offsets)
+	return nil
+}
+
+// prayerCalendarRepository reads the calendars persisted in postgres so the
+// calendar store can warm redis after a flush.
+type prayerCalendarRepository struct{}
+
+func (prayerCalendarRepository) GetPrayerCalendar(
+	ctx context.Context,
+	location prayer.Location,
+	from time.Time,
+	to time.Time,
+) (prayer.PrayerCalendar, error) {
+	rows, err := Queries.GetPrayerCalendar(ctx, repository.GetPrayerCalendarParams{
+		LocationKey: location.Key(),
+		FromDate:    pgtype.Date{Time: time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+		ToDate:      pgtype.Date{Time: time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC), Valid: true},
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get prayer calendar")
+	}
+
+	prayerCalendar := make(prayer.PrayerCalendar, len(rows))
+	for i, row := range rows {
+		err = json.Unmarshal(row.Prayers, &prayerCalendar[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal prayers")
+		}
+	}
+
+	return prayerCalendar, nil
+}
@@ -1,14 +1,21 @@
 package services
 
 import (
+	"github.com/mdayat/demi-masa/pkg/prayer"
 	"github.com/redis/go-redis/v9"
 )
 
 var (
-	RedisClient *redis.Client
+	RedisClient         *redis.Client
+	PrayerCalendarStore prayer.CalendarStore
 )
 
 func InitRedis(REDIS_URL string) *redis.Client {
 	RedisClient = redis.NewClient(&redis.Options{Addr: REDIS_URL})
+	InitPrayerCalendarStore(RedisClient)
 	return RedisClient
 }
+
+func InitPrayerCalendarStore(redisClient *redis.Client) {
+	PrayerCalendarStore = prayer.NewCalendarStore(redisClient, prayerCalendarRepository{})
+}
@@ -11,18 +11,23 @@ var TypeInitialTask = "initial"
 func InitApp() (*asynq.Server, *asynq.ServeMux) {
 	asynqServer := asynq.NewServer(
 		asynq.RedisClientOpt{Addr: env.REDIS_URL},
-		asynq.Config{Concurrency: 10},
+		asynq.Config{Concurrency: 10, RetryDelayFunc: RetryDelay},
 	)
 
+	return asynqServer, NewServeMux()
+}
+
+func NewServeMux() *asynq.ServeMux {
 	mux := asynq.NewServeMux()
-	mux.Use(logger)
+	mux.Use(logger, classifier)
 	mux.HandleFunc(TypeInitialTask, handleInitialTask)
 	mux.HandleFunc(task.TypeUserDowngrade, handleUserDowngrade)
-	mux.HandleFunc(task.TypePrayerReminder, handlePrayerReminder)
-	mux.HandleFunc(task.TypeLastPrayerReminder, handleLastPrayerReminder)
+	mux.HandleFunc(task.TypePrayerFanout, handlePrayerFanout)
 	mux.HandleFunc(task.TypePrayerRenewal, handlePrayerRenewal)
+	mux.HandleFunc(task.TypePrayerCalendarInit, handlePrayerCalendarInit)
 	mux.HandleFunc(task.TypeTaskRemoval, handleTaskRemoval)
 	mux.HandleFunc(task.TypePrayerUpdate, handlePrayerUpdate)
+	mux.HandleFunc(task.TypeReminderReconcile, handleReminderReconcile)
 
-	return asynqServer, mux
+	return mux
 }
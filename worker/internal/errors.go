@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/pkg/notifier"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/pkg/errors"
+)
+
+// errorKind tells how a failed task is retried. A permanent failure is not
+// retried at all, a delivery failure waits for the provider to recover and
+// a store failure for postgres or redis to come back.
+type errorKind string
+
+const (
+	permanentErrorKind errorKind = "permanent"
+	deliveryErrorKind  errorKind = "delivery"
+	storeErrorKind     errorKind = "store"
+	unknownErrorKind   errorKind = "unknown"
+)
+
+// Postgres reports a violated constraint with a code of this class, the
+// same statement fails the same way on every attempt.
+const integrityConstraintViolationClass = "23"
+
+const (
+	deliveryRetryBaseDelay = time.Minute
+	storeRetryBaseDelay    = 10 * time.Second
+	maxRetryDelay          = time.Hour
+)
+
+func classifyError(err error) errorKind {
+	var syntaxError *json.SyntaxError
+	var unmarshalTypeError *json.UnmarshalTypeError
+	var pgError *pgconn.PgError
+	var netError net.Error
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows),
+		errors.Is(err, prayer.ErrNoTimeZoneLocation),
+		errors.Is(err, message.ErrTemplateNotFound),
+		errors.Is(err, notifier.ErrUnreachable),
+		errors.As(err, &syntaxError),
+		errors.As(err, &unmarshalTypeError):
+		return permanentErrorKind
+	case errors.As(err, &pgError):
+		if strings.HasPrefix(pgError.Code, integrityConstraintViolationClass) {
+			return permanentErrorKind
+		}
+		return storeErrorKind
+	case errors.Is(err, notifier.ErrNotDelivered):
+		return deliveryErrorKind
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netError):
+		return storeErrorKind
+	default:
+		return unknownErrorKind
+	}
+}
+
+// RetryDelay backs off from a base delay of the error's kind, doubling on
+// every retry, and leaves unknown errors to asynq's default.
+func RetryDelay(retried int, err error, asynqTask *asynq.Task) time.Duration {
+	var baseDelay time.Duration
+	switch classifyError(err) {
+	case deliveryErrorKind:
+		baseDelay = deliveryRetryBaseDelay
+	case storeErrorKind:
+		baseDelay = storeRetryBaseDelay
+	default:
+		return asynq.DefaultRetryDelayFunc(retried, err, asynqTask)
+	}
+
+	delay := baseDelay << min(retried, 10)
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,435 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/pkg/task"
+	"github.com/mdayat/demi-masa/worker/configs/services"
+	"github.com/mdayat/demi-masa/worker/repository"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// The reminders of a prayer go out through one fan-out per location rather
+// than a task chain per user. Every run pages through the users of the
+// location, works out their reminders from their settings at that moment,
+// queues the next run at the earliest reminder still ahead and then sends
+// the ones that fell due since the previous run.
+const (
+	fanoutPageSize    = 500
+	fanoutConcurrency = 20
+	// Reminders older than this, left over while the worker was down, are
+	// dropped rather than sent late.
+	fanoutMaxDelay = 30 * time.Minute
+)
+
+// Sahur reminders go out this long before imsak.
+const sahurReminderLead = 30 * time.Minute
+
+// sunnahReminders are sent by the fan-out of the prayer they precede.
+var sunnahReminders = []struct {
+	timeName   string
+	prayerName string
+	kind       message.Kind
+}{
+	{timeName: prayer.TahajudTimeName, prayerName: prayer.SubuhPrayerName, kind: message.TahajudReminderKind},
+	{timeName: prayer.DhuhaStartTimeName, prayerName: prayer.ZuhurPrayerName, kind: message.DhuhaReminderKind},
+}
+
+type fanoutReminder struct {
+	kind     message.Kind
+	unixTime int64
+	// prayerUnixTime is the time of the prayer after the user's adjustment.
+	prayerUnixTime int64
+	data           message.Data
+	channel        string
+}
+
+type userReminder struct {
+	user     *repository.GetUsersByLocationKeyRow
+	reminder fanoutReminder
+}
+
+type fanoutResult struct {
+	sent   int
+	failed int
+	// skipped are the reminders that failed for good, such as to a user
+	// without an address on any channel.
+	skipped int
+}
+
+// fanoutPrayers are the prayers of the date as one adjustment sees them.
+// Most users of a location share a handful of adjustments, so a run only
+// reads them once each.
+type fanoutPrayers struct {
+	prayers prayer.Prayers
+	// endUnixTime closes the window of the prayer for its last reminder.
+	endUnixTime int64
+}
+
+// prayerFanout is a run of the fan-out. The users of the location are paged
+// through twice, once to queue the next run and once to send the due
+// reminders, so that a run failing halfway never breaks the chain.
+type prayerFanout struct {
+	payload    *task.PrayerFanoutPayload
+	now        time.Time
+	prayerTime time.Time
+	// prayersByAdjustment holds nil prayers for an invalid adjustment.
+	prayersByAdjustment map[string]fanoutPrayers
+}
+
+func newPrayerFanout(payload *task.PrayerFanoutPayload, now time.Time) *prayerFanout {
+	return &prayerFanout{
+		payload:             payload,
+		now:                 now,
+		prayerTime:          time.Unix(payload.PrayerUnixTime, 0).In(now.Location()),
+		prayersByAdjustment: make(map[string]fanoutPrayers),
+	}
+}
+
+// enqueuePrayerFanout queues the run, callers tell a run that is already
+// queued from a failure by asynq.ErrTaskIDConflict.
+func enqueuePrayerFanout(payload task.PrayerFanoutPayload, now time.Time) error {
+	newAsynqTask, err := task.NewPrayerFanoutTask(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to create prayer fanout task")
+	}
+
+	runTime := time.Unix(payload.RunUnixTime, 0)
+	_, err = services.AsynqClient.Enqueue(newAsynqTask, asynq.ProcessIn(runTime.Sub(now)))
+	if err != nil {
+		return errors.Wrap(err, "failed to enqueue prayer fanout task")
+	}
+
+	return nil
+}
+
+func getFanoutPrayers(
+	ctx context.Context,
+	location prayer.Location,
+	adjustment prayer.Adjustment,
+	prayerName string,
+	prayerTime time.Time,
+) (fanoutPrayers, error) {
+	prayers, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, location, adjustment, prayerTime)
+	if err != nil {
+		return fanoutPrayers{}, errors.Wrap(err, "failed to get prayers for date")
+	}
+
+	userPrayer, ok := prayers.Get(prayerName)
+	if !ok {
+		return fanoutPrayers{}, errors.New(fmt.Sprintf("missing %s prayer", prayerName))
+	}
+
+	if prayerName == prayer.SubuhPrayerName {
+		sunrise, _ := prayers.Get(prayer.SunriseTimeName)
+		return fanoutPrayers{prayers: prayers, endUnixTime: sunrise.UnixTime}, nil
+	}
+
+	nextPrayer, err := services.PrayerCalendarStore.NextPrayerAfter(ctx, location, adjustment, time.Unix(userPrayer.UnixTime, 0))
+	if err != nil {
+		return fanoutPrayers{}, errors.Wrap(err, "failed to get next prayer")
+	}
+
+	return fanoutPrayers{prayers: prayers, endUnixTime: nextPrayer.UnixTime}, nil
+}
+
+// getUserReminders returns every reminder the user gets for the prayer: the
+// prayer reminder itself, the last reminder of premium users who have not
+// checked the prayer yet, and the ramadan and sunnah reminders leading up
+// to it.
+func getUserReminders(
+	user *repository.GetUsersByLocationKeyRow,
+	prayerName string,
+	userPrayers fanoutPrayers,
+	location *time.Location,
+) []fanoutReminder {
+	reminderPreference := prayer.DefaultReminderPreference
+	if user.Enabled.Valid {
+		reminderPreference = prayer.ReminderPreference{
+			Enabled:             user.Enabled.Bool,
+			LeadMinutes:         int(user.LeadMinutes.Int16),
+			LastReminderPercent: int(user.LastReminderPercent.Int16),
+			Channel:             user.Channel.String,
+		}
+	}
+
+	userPrayer, _ := userPrayers.prayers.Get(prayerName)
+	prayerTime := time.Unix(userPrayer.UnixTime, 0).In(location)
+
+	var reminders []fanoutReminder
+	if reminderPreference.Enabled {
+		reminders = append(reminders, fanoutReminder{
+			kind:           message.PrayerReminderKind,
+			unixTime:       reminderPreference.ReminderUnixTime(userPrayer.UnixTime),
+			prayerUnixTime: userPrayer.UnixTime,
+			data:           message.Data{PrayerName: prayerName, Time: prayerTime.Format("15:04")},
+			channel:        reminderPreference.Channel,
+		})
+	}
+
+	isLastReminderEnabled := reminderPreference.Enabled && reminderPreference.LastReminderPercent != 0
+	if user.AccountType == repository.AccountTypePREMIUM && isLastReminderEnabled && user.PrayerStatus.Valid == false {
+		reminders = append(reminders, fanoutReminder{
+			kind:           message.LastPrayerReminderKind,
+			unixTime:       reminderPreference.LastReminderUnixTime(userPrayer.UnixTime, userPrayers.endUnixTime),
+			prayerUnixTime: userPrayer.UnixTime,
+			data:           message.Data{PrayerName: prayerName},
+			channel:        reminderPreference.Channel,
+		})
+	}
+
+	if prayerName == prayer.SubuhPrayerName && services.HijriCalendar.FromGregorian(prayerTime).IsRamadan() {
+		imsakTime := prayer.GetImsakTime(userPrayer)
+		data := message.Data{Time: time.Unix(imsakTime.UnixTime, 0).In(location).Format("15:04")}
+		reminders = append(
+			reminders,
+			fanoutReminder{
+				kind:           message.SahurReminderKind,
+				unixTime:       imsakTime.UnixTime - int64(sahurReminderLead.Seconds()),
+				prayerUnixTime: userPrayer.UnixTime,
+				data:           data,
+			},
+			fanoutReminder{
+				kind:           message.ImsakReminderKind,
+				unixTime:       imsakTime.UnixTime,
+				prayerUnixTime: userPrayer.UnixTime,
+				data:           data,
+			},
+		)
+	}
+
+	if user.SunnahReminder {
+		for _, sunnahReminder := range sunnahReminders {
+			if sunnahReminder.prayerName != prayerName {
+				continue
+			}
+
+			sunnahTime, ok := userPrayers.prayers.Get(sunnahReminder.timeName)
+			if !ok {
+				continue
+			}
+
+			reminders = append(reminders, fanoutReminder{
+				kind:           sunnahReminder.kind,
+				unixTime:       sunnahTime.UnixTime,
+				prayerUnixTime: userPrayer.UnixTime,
+			})
+		}
+	}
+
+	return reminders
+}
+
+// getPrayerReminderMessage tells a lead reminder from a plain one by when it
+// goes out, a lead reminder sent after the prayer started becomes plain.
+func getPrayerReminderMessage(ctx context.Context, userID string, reminder fanoutReminder, now time.Time) (message.Kind, message.Data, error) {
+	prayerTime := time.Unix(reminder.prayerUnixTime, 0).In(now.Location())
+	messageData := reminder.data
+	if reminder.unixTime < reminder.prayerUnixTime && prayerTime.After(now) {
+		messageData.Minutes = int(prayerTime.Sub(now).Round(time.Minute).Minutes())
+		return message.PrayerLeadReminderKind, messageData, nil
+	}
+
+	if messageData.PrayerName == prayer.MagribPrayerName && services.HijriCalendar.FromGregorian(prayerTime).IsRamadan() {
+		return message.IftarReminderKind, messageData, nil
+	}
+
+	streak, err := services.Queries.GetUserPrayerStreak(ctx, userID)
+	if err != nil {
+		return "", message.Data{}, errors.Wrap(err, "failed to get user prayer streak")
+	}
+
+	messageData.Streak = streak
+	return message.PrayerReminderKind, messageData, nil
+}
+
+func sendFanoutReminder(ctx context.Context, user *repository.GetUsersByLocationKeyRow, reminder fanoutReminder, now time.Time) error {
+	isSkipped, err := isDoNotDisturb(user.QuietWindows, user.RemindersPausedUntil, now)
+	if err != nil {
+		return errors.Wrap(err, "failed to check do not disturb")
+	}
+
+	if isSkipped {
+		log.Ctx(ctx).Info().Str("user_id", user.ID).Str("kind", string(reminder.kind)).Msg("do not disturb active, notification skipped")
+		return nil
+	}
+
+	messageKind, messageData := reminder.kind, reminder.data
+	if messageKind == message.PrayerReminderKind {
+		messageKind, messageData, err = getPrayerReminderMessage(ctx, user.ID, reminder, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	msg, err := services.MessageStore.Render(ctx, messageKind, message.ParseLocale(user.Locale), messageData)
+	if err != nil {
+		return errors.Wrap(err, "failed to render reminder message")
+	}
+
+	return notifyUser(ctx, user.ID, user.PhoneNumber, user.Email, user.NotificationChannels, reminder.channel, messageKind, msg)
+}
+
+// sendFanoutReminders sends the due reminders of a page, at most
+// fanoutConcurrency at a time, and returns how many of them failed and how
+// many were skipped because no retry would deliver them.
+func sendFanoutReminders(ctx context.Context, dueReminders []userReminder, now time.Time) (int, int) {
+	var wg sync.WaitGroup
+	var failed, skipped atomic.Int64
+	semaphore := make(chan struct{}, fanoutConcurrency)
+
+	for _, v := range dueReminders {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(v userReminder) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := sendFanoutReminder(ctx, v.user, v.reminder, now)
+			if err == nil {
+				return
+			}
+
+			if classifyError(err) == permanentErrorKind {
+				log.Ctx(ctx).
+					Warn().
+					Err(err).
+					Str("user_id", v.user.ID).
+					Str("kind", string(v.reminder.kind)).
+					Msg("reminder cannot be delivered, skipped")
+
+				skipped.Add(1)
+				return
+			}
+
+			log.Ctx(ctx).
+				Error().
+				Err(err).
+				Caller().
+				Str("user_id", v.user.ID).
+				Str("kind", string(v.reminder.kind)).
+				Msg("failed to send reminder")
+
+			failed.Add(1)
+		}(v)
+	}
+
+	wg.Wait()
+	return int(failed.Load()), int(skipped.Load())
+}
+
+// forEachPage pages through the users of the location and calls fn with
+// every reminder they get for the prayer.
+func (f *prayerFanout) forEachPage(ctx context.Context, fn func(reminders []userReminder) error) error {
+	location := f.now.Location()
+	afterID := ""
+	for {
+		users, err := services.Queries.GetUsersByLocationKey(ctx, repository.GetUsersByLocationKeyParams{
+			PrayerName:  f.payload.PrayerName,
+			Year:        int16(f.prayerTime.Year()),
+			Month:       int16(f.prayerTime.Month()),
+			Day:         int16(f.prayerTime.Day()),
+			LocationKey: pgtype.Text{String: f.payload.Location.Key(), Valid: true},
+			AfterID:     afterID,
+			PageSize:    fanoutPageSize,
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "failed to get users by location key")
+		}
+
+		var reminders []userReminder
+		for i := range users {
+			user := &users[i]
+			adjustmentKey := string(user.AsrMethod) + string(user.PrayerOffsets)
+			userPrayers, ok := f.prayersByAdjustment[adjustmentKey]
+			if !ok {
+				adjustment, err := prayer.NewAdjustment(string(user.AsrMethod), user.PrayerOffsets)
+				if err != nil {
+					log.Ctx(ctx).Warn().Err(err).Caller().Str("user_id", user.ID).Msg("invalid prayer adjustment, reminders skipped")
+					f.prayersByAdjustment[adjustmentKey] = fanoutPrayers{}
+					continue
+				}
+
+				userPrayers, err = getFanoutPrayers(ctx, f.payload.Location, adjustment, f.payload.PrayerName, f.prayerTime)
+				if err != nil {
+					return err
+				}
+				f.prayersByAdjustment[adjustmentKey] = userPrayers
+			}
+
+			if userPrayers.prayers == nil {
+				continue
+			}
+
+			for _, reminder := range getUserReminders(user, f.payload.PrayerName, userPrayers, location) {
+				reminders = append(reminders, userReminder{user: user, reminder: reminder})
+			}
+		}
+
+		err = fn(reminders)
+		if err != nil {
+			return err
+		}
+
+		if len(users) < fanoutPageSize {
+			return nil
+		}
+		afterID = users[len(users)-1].ID
+	}
+}
+
+// getNextUnixTime returns the earliest reminder after the run, zero when
+// none is left.
+func (f *prayerFanout) getNextUnixTime(ctx context.Context) (int64, error) {
+	var nextUnixTime int64
+	err := f.forEachPage(ctx, func(reminders []userReminder) error {
+		for _, v := range reminders {
+			if v.reminder.unixTime <= f.payload.RunUnixTime {
+				continue
+			}
+
+			if nextUnixTime == 0 || v.reminder.unixTime < nextUnixTime {
+				nextUnixTime = v.reminder.unixTime
+			}
+		}
+		return nil
+	})
+
+	return nextUnixTime, err
+}
+
+// run sends the reminders of the prayer between the payload's FromUnixTime
+// and RunUnixTime to the users of the location, page by page. A failed
+// reminder does not stop the run, the others still go out and the failures
+// are counted.
+func (f *prayerFanout) run(ctx context.Context) (fanoutResult, error) {
+	var result fanoutResult
+	err := f.forEachPage(ctx, func(reminders []userReminder) error {
+		var dueReminders []userReminder
+		for _, v := range reminders {
+			isStale := f.now.Unix()-v.reminder.unixTime > int64(fanoutMaxDelay.Seconds())
+			if v.reminder.unixTime < f.payload.FromUnixTime || v.reminder.unixTime > f.payload.RunUnixTime || isStale {
+				continue
+			}
+			dueReminders = append(dueReminders, v)
+		}
+
+		failed, skipped := sendFanoutReminders(ctx, dueReminders, f.now)
+		result.sent += len(dueReminders) - failed - skipped
+		result.failed += failed
+		result.skipped += skipped
+		return nil
+	})
+
+	return result, err
+}
@@ -4,19 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math"
-	"strconv"
+	"slices"
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/pkg/notifier"
 	"github.com/mdayat/demi-masa/pkg/prayer"
 	"github.com/mdayat/demi-masa/pkg/task"
 	"github.com/mdayat/demi-masa/worker/configs/services"
 	"github.com/mdayat/demi-masa/worker/repository"
 	"github.com/pkg/errors"
-	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
-	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
 func handleInitialTask(ctx context.Context, _ *asynq.Task) error {
@@ -46,171 +47,199 @@ func handleUserDowngrade(ctx context.Context, asynqTask *asynq.Task) error {
 	return nil
 }
 
-func handlePrayerReminder(ctx context.Context, asynqTask *asynq.Task) error {
-	start := time.Now()
+// notificationRetryWindow comfortably covers the backoff of every retry of
+// a task.
+const notificationRetryWindow = time.Hour
+
+// notifyUser sends the message over the user's preferred channels, falling
+// back in the order they picked. The preferred channel of a reminder, if
+// any, is tried first. Every attempt is recorded as a notification, which
+// also keeps a retried task from sending the same message twice.
+//
+// A fan-out run sends many users under one task id, so only a retry looks
+// for the user's message of the same kind sent by an earlier attempt, and
+// only within the retry window.
+func notifyUser(
+	ctx context.Context,
+	userID string,
+	phoneNumber pgtype.Text,
+	email string,
+	channelsJSON []byte,
+	preferredChannel string,
+	kind message.Kind,
+	msg notifier.Message,
+) error {
 	logWithCtx := log.Ctx(ctx).With().Logger()
-	var payload task.PrayerReminderPayload
-	if err := json.Unmarshal(asynqTask.Payload(), &payload); err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to unmarshal prayer reminder task payload")
-		return err
-	}
+	taskID, _ := asynq.GetTaskID(ctx)
+	taskIDText := pgtype.Text{String: taskID, Valid: taskID != ""}
+	retryCount, _ := asynq.GetRetryCount(ctx)
+	if taskIDText.Valid && retryCount > 0 {
+		_, err := services.Queries.GetSentNotificationByTaskID(ctx, repository.GetSentNotificationByTaskIDParams{
+			TaskID:    taskIDText,
+			UserID:    userID,
+			Kind:      string(kind),
+			CreatedAt: pgtype.Timestamptz{Time: services.Clock.Now().Add(-notificationRetryWindow), Valid: true},
+		})
 
-	user, err := services.Queries.GetUserPrayerByID(ctx, payload.UserID)
-	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Str("user_id", payload.UserID).Msg("failed to get user prayer by id")
-		return err
+		if err == nil {
+			logWithCtx.Warn().Str("task_id", taskID).Str("user_id", userID).Msg("notification already sent by a previous attempt, skipped")
+			return nil
+		}
+
+		if errors.Is(err, pgx.ErrNoRows) == false {
+			return errors.Wrap(err, "failed to get sent notification by task id")
+		}
 	}
 
-	location, err := time.LoadLocation(string(user.TimeZone.IndonesiaTimeZone))
+	channels, err := notifier.ParseChannels(channelsJSON)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to load time zone location")
 		return err
 	}
 
-	prayerCalendar, err := prayer.GetPrayerCalendar(ctx, services.RedisClient, string(user.TimeZone.IndonesiaTimeZone))
-	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to get prayer calendar")
-		return err
+	if preferredChannel != "" {
+		channels = slices.DeleteFunc(channels, func(channel notifier.Channel) bool {
+			return channel == notifier.Channel(preferredChannel)
+		})
+		channels = slices.Insert(channels, 0, notifier.Channel(preferredChannel))
 	}
 
-	prayerTime := time.Unix(payload.PrayerUnixTime, 0).In(location)
-	isLastDay := prayer.IsLastDay(prayerTime)
-	isPenultimateDay := prayer.IsPenultimateDay(prayerTime)
+	recipient := notifier.Recipient{UserID: userID, PhoneNumber: phoneNumber.String, Email: email}
+	delivery, sendErr := services.Notifier.Send(ctx, channels, recipient, msg)
 
-	var lastDayPrayer prayer.Prayers
-	if payload.IsLastDay && payload.PrayerName != prayer.IsyaPrayerName {
-		lastDayPrayer, err = prayer.GetLastDayPrayer(ctx, services.RedisClient, string(user.TimeZone.IndonesiaTimeZone))
-		if err != nil {
-			logWithCtx.Error().Err(err).Caller().Msg("failed to get last day prayer")
-			return err
-		}
+	params := repository.CreateNotificationParams{
+		UserID:      userID,
+		TaskID:      taskIDText,
+		Kind:        string(kind),
+		Channel:     pgtype.Text{String: string(delivery.Channel), Valid: delivery.Channel != ""},
+		ProviderSid: pgtype.Text{String: delivery.ProviderID, Valid: delivery.ProviderID != ""},
+		Status:      string(delivery.Status),
 	}
 
-	var isNextPrayerLastDay bool
-	if (isPenultimateDay && payload.PrayerName == prayer.IsyaPrayerName) ||
-		(isLastDay && payload.PrayerName != prayer.IsyaPrayerName) {
-		isNextPrayerLastDay = true
+	if sendErr != nil {
+		params.Status = string(notifier.FailedStatus)
+		params.Error = pgtype.Text{String: sendErr.Error(), Valid: true}
 	}
 
-	prayerDay := prayerTime.Day()
-	if payload.IsLastDay && payload.PrayerName == prayer.IsyaPrayerName {
-		prayerDay = 1
-	}
+	err = services.Queries.CreateNotification(ctx, params)
+	if err != nil {
+		if sendErr != nil {
+			return sendErr
+		}
 
-	nextPrayer := prayer.GetNextPrayerByName(prayerCalendar, lastDayPrayer, prayerDay, payload.PrayerName)
-	now := time.Now().In(location)
-	nowUnixTime := now.Unix()
+		// The message is already out, failing the task would send it again.
+		logWithCtx.Error().Err(err).Caller().Str("user_id", userID).Msg("failed to create notification")
+	}
 
-	nextPrayerTime := time.Unix(nextPrayer.UnixTime, 0).In(location)
-	newAsynqTask, err := task.NewPrayerReminderTask(task.PrayerReminderPayload{
-		UserID:         payload.UserID,
-		PrayerName:     nextPrayer.Name,
-		PrayerUnixTime: nextPrayer.UnixTime,
-		IsLastDay:      isNextPrayerLastDay,
-	})
+	return sendErr
+}
 
+// isDoNotDisturb reports whether now falls in one of the user's quiet
+// windows or before their reminders pause ends.
+func isDoNotDisturb(quietWindows []byte, pausedUntil pgtype.Timestamptz, now time.Time) (bool, error) {
+	doNotDisturb, err := prayer.NewDoNotDisturb(quietWindows, pausedUntil.Time)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to create prayer reminder task")
+		return false, err
+	}
+	return doNotDisturb.IsActive(now), nil
+}
+
+func handlePrayerFanout(ctx context.Context, asynqTask *asynq.Task) error {
+	start := time.Now()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var payload task.PrayerFanoutPayload
+	if err := json.Unmarshal(asynqTask.Payload(), &payload); err != nil {
+		logWithCtx.Error().Err(err).Caller().Msg("failed to unmarshal prayer fanout task payload")
 		return err
 	}
 
-	_, err = services.AsynqClient.Enqueue(newAsynqTask, asynq.ProcessIn(nextPrayerTime.Sub(now)))
+	logWithCtx = logWithCtx.With().Str("location", payload.Location.Key()).Str("prayer_name", payload.PrayerName).Logger()
+	err := ensurePrayerCalendar(ctx, payload.Location)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to enqueue prayer reminder task")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to ensure prayer calendar")
 		return err
 	}
 
-	if user.AccountType == repository.AccountTypePREMIUM {
-		var prayerTimeDistance int64
-		var nowToNextPrayerDistance int64
-
-		if payload.PrayerName == prayer.SubuhPrayerName && isLastDay {
-			sunriseUnixTime := lastDayPrayer[1].UnixTime
-			prayerTimeDistance = sunriseUnixTime - payload.PrayerUnixTime
-			nowToNextPrayerDistance = sunriseUnixTime - nowUnixTime
-		} else if payload.PrayerName == prayer.SubuhPrayerName && isLastDay == false {
-			todayPrayer := prayerCalendar[prayerDay-1]
-			sunriseUnixTime := todayPrayer[1].UnixTime
-			prayerTimeDistance = sunriseUnixTime - payload.PrayerUnixTime
-			nowToNextPrayerDistance = sunriseUnixTime - nowUnixTime
-		} else {
-			prayerTimeDistance = nextPrayer.UnixTime - payload.PrayerUnixTime
-			nowToNextPrayerDistance = nextPrayer.UnixTime - nowUnixTime
-		}
+	location, err := time.LoadLocation(payload.Location.TimeZone)
+	if err != nil {
+		logWithCtx.Error().Err(err).Caller().Msg("failed to load time zone location")
+		return err
+	}
 
-		newAsynqTask, err = task.NewLastPrayerReminderTask(task.LastPrayerReminderPayload{
-			UserID:     payload.UserID,
-			PrayerName: payload.PrayerName,
-		})
+	// The first run of a prayer queues the first run of the next one, which
+	// starts once this prayer does.
+	now := services.Clock.Now().In(location)
+	if payload.FromUnixTime == 0 {
+		nextPrayer, err := services.PrayerCalendarStore.NextPrayerAfter(
+			ctx,
+			payload.Location,
+			prayer.Adjustment{},
+			time.Unix(payload.PrayerUnixTime, 0),
+		)
 
 		if err != nil {
-			logWithCtx.Error().Err(err).Caller().Msg("failed to create last prayer reminder task")
+			logWithCtx.Error().Err(err).Caller().Msg("failed to get next prayer")
 			return err
 		}
 
-		quarterTime := math.Round(float64(prayerTimeDistance) * 0.25)
-		lastReminderDuration := time.Duration(nowToNextPrayerDistance-int64(quarterTime)) * time.Second
+		err = enqueuePrayerFanout(task.PrayerFanoutPayload{
+			Location:       payload.Location,
+			PrayerName:     nextPrayer.Name,
+			PrayerUnixTime: nextPrayer.UnixTime,
+			RunUnixTime:    payload.PrayerUnixTime,
+		}, now)
 
-		_, err = services.AsynqClient.Enqueue(newAsynqTask, asynq.ProcessIn(lastReminderDuration))
-		if err != nil {
-			logWithCtx.Error().Err(err).Caller().Msg("failed to enqueue last prayer reminder task")
+		if err != nil && errors.Is(err, asynq.ErrTaskIDConflict) == false {
+			logWithCtx.Error().Err(err).Caller().Msg("failed to enqueue next prayer fanout")
 			return err
 		}
 	}
 
-	params := twilioApi.CreateMessageParams{}
-	params.SetFrom("whatsapp:+14155238886")
-	params.SetTo(fmt.Sprintf("whatsapp:%s", user.PhoneNumber.String))
-	msg := fmt.Sprintf(
-		"Hai! Sudah waktunya salat %s nih... Yuk segera tunaikan dan jangan lupa untuk memperbarui kemajuan kamu di aplikasi Demi Masa.",
-		payload.PrayerName,
-	)
-	params.SetBody(msg)
-
-	_, err = services.TwilioClient.Api.CreateMessage(&params)
+	// The next run is queued before any reminder goes out, a failed
+	// delivery is retried without holding up the rest of the prayer.
+	prayerFanout := newPrayerFanout(&payload, now)
+	nextUnixTime, err := prayerFanout.getNextUnixTime(ctx)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Str("phone_number", user.PhoneNumber.String).Msg("failed to send prayer reminder")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to get next prayer fanout time")
 		return err
 	}
-	logWithCtx.Info().Dur("response_time", time.Since(start)).Msg("task completed")
 
-	return nil
-}
+	if nextUnixTime != 0 {
+		err = enqueuePrayerFanout(task.PrayerFanoutPayload{
+			Location:       payload.Location,
+			PrayerName:     payload.PrayerName,
+			PrayerUnixTime: payload.PrayerUnixTime,
+			FromUnixTime:   payload.RunUnixTime + 1,
+			RunUnixTime:    nextUnixTime,
+		}, now)
 
-func handleLastPrayerReminder(ctx context.Context, asynqTask *asynq.Task) error {
-	start := time.Now()
-	logWithCtx := log.Ctx(ctx).With().Logger()
-	var payload task.PrayerReminderPayload
-	if err := json.Unmarshal(asynqTask.Payload(), &payload); err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to unmarshal last prayer reminder task payload")
-		return err
+		if err != nil && errors.Is(err, asynq.ErrTaskIDConflict) == false {
+			logWithCtx.Error().Err(err).Caller().Msg("failed to enqueue prayer fanout")
+			return err
+		}
 	}
 
-	userPhone, err := services.Queries.GetUserPhoneByID(ctx, payload.UserID)
+	result, err := prayerFanout.run(ctx)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Str("user_id", payload.UserID).Msg("failed to get user phone by id")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to run prayer fanout")
 		return err
 	}
 
-	params := twilioApi.CreateMessageParams{}
-	params.SetFrom("whatsapp:+14155238886")
-	params.SetTo(fmt.Sprintf("whatsapp:%s", userPhone.String))
-	msg := fmt.Sprintf(
-		"Waktu salat %s sudah hampir habis. Yuk, segera lakukan sebelum terlambat.",
-		payload.PrayerName,
-	)
-	params.SetBody(msg)
-
-	_, err = services.TwilioClient.Api.CreateMessage(&params)
-	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Str("phone_number", userPhone.String).Msg("failed to send last prayer reminder")
+	if result.failed > 0 {
+		err = errors.Wrap(
+			notifier.ErrNotDelivered,
+			fmt.Sprintf("failed to send %d of %d reminders", result.failed, result.sent+result.failed+result.skipped),
+		)
+		logWithCtx.Error().Err(err).Caller().Send()
 		return err
 	}
-	logWithCtx.Info().Dur("response_time", time.Since(start)).Msg("task completed")
 
+	logWithCtx.Info().Int("sent", result.sent).Int("skipped", result.skipped).Dur("response_time", time.Since(start)).Msg("task completed")
 	return nil
 }
 
+// handlePrayerRenewal stores the months around the current one, the next
+// month included, and prunes the ones before them. A run caught up late
+// leaves the calendar as a run on time would.
 func handlePrayerRenewal(ctx context.Context, asynqTask *asynq.Task) error {
 	start := time.Now()
 	logWithCtx := log.Ctx(ctx).With().Logger()
@@ -220,88 +249,48 @@ func handlePrayerRenewal(ctx context.Context, asynqTask *asynq.Task) error {
 		return err
 	}
 
-	location, err := time.LoadLocation(string(payload.TimeZone))
+	location, err := time.LoadLocation(payload.Location.TimeZone)
 	if err != nil {
 		logWithCtx.Error().Err(err).Caller().Msg("failed to load time zone location")
 		return err
 	}
 
-	now := time.Now().In(location)
-	year := now.Year()
-	month, err := strconv.Atoi(fmt.Sprintf("%d", now.Month()))
+	err = InitPrayerCalendar(ctx, payload.Location)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to convert string to integer")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to init prayer calendar")
 		return err
 	}
 
-	if month == 12 {
-		year++
-		month = 1
-	} else {
-		month++
-	}
-
-	unparsedPrayerCalendar, err := prayer.GetAladhanPrayerCalendar(makeAladhanURL(year, month, payload.TimeZone))
+	now := services.Clock.Now().In(location)
+	firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+	err = services.PrayerCalendarStore.PruneBefore(ctx, payload.Location, firstDay.AddDate(0, -1, 0))
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to get aladhan prayer calendar")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to prune prayer calendar")
 		return err
 	}
 
-	parsedPrayerCalendar, err := prayer.ParseAladhanPrayerCalendar(unparsedPrayerCalendar, location)
-	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to parse aladhan prayer calendar")
-		return err
-	}
+	logWithCtx.Info().Dur("response_time", time.Since(start)).Msg("task completed")
+	return nil
+}
 
-	parsedPrayerCalendarJSON, err := json.Marshal(parsedPrayerCalendar)
-	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to marshal parsed aladhan prayer calendar")
+func handlePrayerCalendarInit(ctx context.Context, asynqTask *asynq.Task) error {
+	start := time.Now()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var payload task.PrayerCalendarInitPayload
+	if err := json.Unmarshal(asynqTask.Payload(), &payload); err != nil {
+		logWithCtx.Error().Err(err).Caller().Msg("failed to unmarshal prayer calendar init task payload")
 		return err
 	}
 
-	err = services.RedisClient.Watch(ctx, func(tx *redis.Tx) error {
-		oldPrayerCalendar, err := prayer.GetPrayerCalendar(ctx, services.RedisClient, payload.TimeZone)
-		if err != nil {
-			logWithCtx.Error().Err(err).Caller().Msg("failed to get prayer calendar")
-			return err
-		}
-
-		penultimateDayPrayer := parsedPrayerCalendar[len(parsedPrayerCalendar)-2]
-		penultimateDayPrayerJSON, err := json.Marshal(penultimateDayPrayer)
-		if err != nil {
-			logWithCtx.Error().Err(err).Caller().Msg("failed to marshal penultimate day prayer of old prayer calendar")
-			return err
-		}
-
-		lastDayPrayer := oldPrayerCalendar[len(oldPrayerCalendar)-1]
-		lastDayPrayerJSON, err := json.Marshal(lastDayPrayer)
-		if err != nil {
-			logWithCtx.Error().Err(err).Caller().Msg("failed to marshal last day prayer of old prayer calendar")
-			return err
-		}
-
-		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-			pipe.Set(ctx, prayer.MakePrayerCalendarKey(payload.TimeZone), parsedPrayerCalendarJSON, 0)
-			pipe.Set(ctx, prayer.MakeLastDayPrayerKey(payload.TimeZone), lastDayPrayerJSON, 0)
-			pipe.Set(ctx, prayer.MakePenultimateDayPrayerKey(payload.TimeZone), penultimateDayPrayerJSON, 0)
-			return nil
-		})
-
-		return err
-	})
-
-	newAsynqTask, err := task.NewPrayerRenewalTask(task.PrayerRenewalTask{TimeZone: payload.TimeZone, Month: month})
+	err := ensurePrayerCalendar(ctx, payload.Location)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to create prayer renewal task")
+		logWithCtx.Error().Err(err).Caller().Str("location", payload.Location.Key()).Msg("failed to ensure prayer calendar")
 		return err
 	}
 
-	numOfDays := len(parsedPrayerCalendar)
-	renewalDate := time.Date(year, time.Month(month), numOfDays, 0, 0, 0, 0, now.Location())
-
-	_, err = services.AsynqClient.Enqueue(newAsynqTask, asynq.ProcessIn(renewalDate.Sub(now)))
+	_, err = initPrayerFanout(ctx, payload.Location)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to enqueue prayer renewal task")
+		logWithCtx.Error().Err(err).Caller().Str("location", payload.Location.Key()).Msg("failed to init prayer fanout")
 		return err
 	}
 
@@ -318,25 +307,41 @@ func handleTaskRemoval(ctx context.Context, _ *asynq.Task) error {
 		return err
 	}
 
-	location, err := time.LoadLocation(string(repository.IndonesiaTimeZoneAsiaJakarta))
-	if err != nil {
-		return errors.Wrap(err, "failed to load time zone location")
-	}
+	logWithCtx.Info().Dur("response_time", time.Since(start)).Msg("task completed")
+	return nil
+}
 
-	now := time.Now().In(location)
-	tomorrow := now.AddDate(0, 0, 1).In(now.Location())
-	midnight := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 0, 0, 0, 0, tomorrow.Location())
-	day := midnight.Day()
+// handlePrayerUpdate marks the unchecked prayers before the day of the last
+// scheduled run as missed, so a run caught up before the next one does not
+// cut the grace of the current day short.
+func handlePrayerUpdate(ctx context.Context, asynqTask *asynq.Task) error {
+	start := time.Now()
+	logWithCtx := log.Ctx(ctx).With().Logger()
+	var payload task.PrayerUpdatePayload
+	if err := json.Unmarshal(asynqTask.Payload(), &payload); err != nil {
+		logWithCtx.Error().Err(err).Caller().Msg("failed to unmarshal prayer update task payload")
+		return err
+	}
 
-	newAsynqTask, err := task.NewTaskRemovalTask(day)
+	location, err := time.LoadLocation(payload.TimeZone)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to create task removal task")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to load time zone location")
 		return err
 	}
 
-	_, err = services.AsynqClient.Enqueue(newAsynqTask, asynq.ProcessIn(midnight.Sub(now)))
+	now := services.Clock.Now().In(location)
+	if now.Hour() < prayerUpdateHour {
+		now = now.AddDate(0, 0, -1)
+	}
+
+	err = services.Queries.UpdatePrayersToMissed(ctx, repository.UpdatePrayersToMissedParams{
+		Day:      int16(now.Day()),
+		Month:    int16(now.Month()),
+		Year:     int16(now.Year()),
+		TimeZone: pgtype.Text{String: payload.TimeZone, Valid: true},
+	})
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to enqueue task removal task")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to update prayers status to missed")
 		return err
 	}
 
@@ -344,42 +349,48 @@ func handleTaskRemoval(ctx context.Context, _ *asynq.Task) error {
 	return nil
 }
 
-func handlePrayerUpdate(ctx context.Context, _ *asynq.Task) error {
+func handleReminderReconcile(ctx context.Context, _ *asynq.Task) error {
 	start := time.Now()
 	logWithCtx := log.Ctx(ctx).With().Logger()
-	location, err := time.LoadLocation(string(repository.IndonesiaTimeZoneAsiaJakarta))
+
+	var result reconcileResult
+	locationKeys, err := reconcileFanouts(ctx, &result)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to load time zone location")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to reconcile prayer fanouts")
 		return err
 	}
 
-	now := time.Now().In(location)
-	err = services.Queries.UpdatePrayersToMissed(ctx, repository.UpdatePrayersToMissedParams{
-		Day:   int16(now.Day()),
-		Month: int16(now.Month()),
-		Year:  int16(now.Year()),
-	})
+	now := services.Clock.Now()
+	err = requeueArchivedFanouts(ctx, locationKeys, now, &result)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to update prayers status to missed")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to requeue archived prayer fanouts")
 		return err
 	}
 
-	tomorrow := now.AddDate(0, 0, 1).In(now.Location())
-	tomorrowAtSix := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 6, 0, 0, 0, tomorrow.Location())
-	day := tomorrowAtSix.Day()
-
-	newAsynqTask, err := task.NewPrayerUpdateTask(day)
+	err = removeOrphanTasks(ctx, locationKeys, &result)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to create prayer update task")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to remove orphan tasks")
 		return err
 	}
 
-	_, err = services.AsynqClient.Enqueue(newAsynqTask, asynq.ProcessIn(tomorrowAtSix.Sub(now)))
+	result.caughtUpTasks, err = CatchUpPeriodicTasks(ctx)
 	if err != nil {
-		logWithCtx.Error().Err(err).Caller().Msg("failed to enqueue prayer update task")
+		logWithCtx.Error().Err(err).Caller().Msg("failed to catch up periodic tasks")
 		return err
 	}
 
-	logWithCtx.Info().Dur("response_time", time.Since(start)).Msg("task completed")
+	logWithCtx.
+		Info().
+		Int("locations", result.locations).
+		Int("failed_locations", result.failedLocations).
+		Int("restarted_fanouts", result.restartedFanouts).
+		Int("requeued_fanouts", result.requeuedFanouts).
+		Int("abandoned_fanouts", result.abandonedFanouts).
+		Int("removed_orphans", result.removedOrphans).
+		Int("caught_up_tasks", result.caughtUpTasks).
+		Bool("alert", result.failedLocations+result.restartedFanouts+result.requeuedFanouts+result.abandonedFanouts+result.caughtUpTasks > 0).
+		Dur("response_time", time.Since(start)).
+		Msg("task completed")
+
 	return nil
 }
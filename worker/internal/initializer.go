@@ -3,298 +3,361 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
-	"strconv"
-	"strings"
+	"slices"
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/mdayat/demi-masa/pkg/prayer"
 	"github.com/mdayat/demi-masa/pkg/task"
 	"github.com/mdayat/demi-masa/worker/configs/services"
 	"github.com/mdayat/demi-masa/worker/repository"
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
 )
 
-func makeAladhanURL(year, month int, timeZone string) string {
-	return fmt.Sprintf(
-		"https://api.aladhan.com/v1/calendarByCity/%d/%d?country=Indonesia&city=%s",
-		year,
-		month,
-		strings.Split(timeZone, "/")[1],
-	)
-}
+// DefaultTimeZone runs the daily jobs that are not tied to the users' time
+// zones.
+const DefaultTimeZone = "Asia/Jakarta"
+
+// errNoPrayerCalendar tells a location no provider could serve apart from
+// the store failing, the other locations can still go ahead without it.
+var errNoPrayerCalendar = errors.New("no prayer time provider served the calendar")
+
+// getPrayerCalendar serves the month from the first provider whose calendar
+// passes validation, cross-checked against the next provider that can serve
+// one. A rejected calendar is never stored, so the previous one stays in use.
+// When no provider serves one, the error joins the failure of every provider.
+func getPrayerCalendar(ctx context.Context, location prayer.Location, year, month int) (prayer.PrayerCalendar, string, error) {
+	logWithCtx := log.Ctx(ctx).With().Str("location", location.Key()).Int("year", year).Int("month", month).Logger()
+	failures := make([]error, 0, len(services.PrayerProviders))
+	for i, provider := range services.PrayerProviders {
+		prayerCalendar, err := provider.GetPrayerCalendar(ctx, location, year, month)
+		if err != nil {
+			logWithCtx.Warn().Err(err).Caller().Str("provider", provider.Name()).Msg("prayer time provider failed, falling back")
+			failures = append(failures, errors.Wrap(err, provider.Name()))
+			continue
+		}
 
-func InitPrayerCalendar(ctx context.Context, location *time.Location) error {
-	now := time.Now().In(location)
-	isLastDay := prayer.IsLastDay(now)
+		err = prayer.ValidatePrayerCalendar(prayerCalendar, location, year, month)
+		if err != nil {
+			logWithCtx.Error().Err(err).Caller().Str("provider", provider.Name()).Bool("alert", true).Msg("prayer calendar rejected")
+			failures = append(failures, errors.Wrap(err, provider.Name()))
+			continue
+		}
 
-	month, err := strconv.Atoi(fmt.Sprintf("%d", now.Month()))
-	if err != nil {
-		return errors.Wrap(err, "failed to convert string to integer")
-	}
+		for _, otherProvider := range services.PrayerProviders[i+1:] {
+			otherPrayerCalendar, err := otherProvider.GetPrayerCalendar(ctx, location, year, month)
+			if err != nil || prayer.ValidatePrayerCalendar(otherPrayerCalendar, location, year, month) != nil {
+				continue
+			}
 
-	year := now.Year()
-	if isLastDay {
-		if month == 12 {
-			year++
-			month = 1
-		} else {
-			month++
+			err = prayer.ComparePrayerCalendars(prayerCalendar, otherPrayerCalendar, prayer.MaxProviderDrift)
+			if err != nil {
+				logWithCtx.
+					Error().
+					Err(err).
+					Caller().
+					Str("provider", provider.Name()).
+					Str("other_provider", otherProvider.Name()).
+					Bool("alert", true).
+					Msg("prayer calendar rejected")
+
+				return nil, "", fmt.Errorf("%w: %w", errNoPrayerCalendar, errors.Wrap(err, "prayer time providers disagree"))
+			}
+			break
 		}
-	}
-
-	prayerRenewalTaskID := task.MakePrayerRenewalTaskID(location.String(), month)
-	_, err = services.AsynqInspector.GetTaskInfo(task.DefaultQueue, prayerRenewalTaskID)
-	if err != nil && errors.Is(err, asynq.ErrQueueNotFound) {
-		return err
-	}
 
-	if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
-		return errors.Wrap(err, "failed to get prayer renewal task info by id")
+		logWithCtx.Info().Str("provider", provider.Name()).Msg("prayer calendar served")
+		return prayerCalendar.WithSunnahTimes(), provider.Name(), nil
 	}
 
-	if err == nil {
-		return nil
-	}
+	return nil, "", fmt.Errorf("%w: %w", errNoPrayerCalendar, stderrors.Join(failures...))
+}
 
-	timeZone := location.String()
-	prayerCalendar, err := prayer.GetAladhanPrayerCalendar(makeAladhanURL(year, month, timeZone))
+func savePrayerCalendar(
+	ctx context.Context,
+	location prayer.Location,
+	providerName string,
+	prayerCalendar prayer.PrayerCalendar,
+) error {
+	timeZone, err := time.LoadLocation(location.TimeZone)
 	if err != nil {
-		return errors.Wrap(err, "failed to get aladhan prayer calendar")
+		return errors.Wrap(err, "failed to load time zone location")
 	}
 
-	parsedPrayerCalendar, err := prayer.ParseAladhanPrayerCalendar(prayerCalendar, location)
-	if err != nil {
-		return errors.Wrap(err, "failed to parse aladhan prayer calendar")
-	}
+	dates := make([]pgtype.Date, len(prayerCalendar))
+	prayersJSON := make([][]byte, len(prayerCalendar))
+	for i, prayers := range prayerCalendar {
+		zuhur, ok := prayers.Get(prayer.ZuhurPrayerName)
+		if !ok {
+			return errors.New("missing zuhur prayer")
+		}
 
-	prayerCalendarJSON, err := json.Marshal(parsedPrayerCalendar)
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal parsed aladhan prayer calendar")
-	}
+		date := time.Unix(zuhur.UnixTime, 0).In(timeZone)
+		dates[i] = pgtype.Date{Time: time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
 
-	penultimateDayPrayer := parsedPrayerCalendar[len(parsedPrayerCalendar)-2]
-	penultimateDayPrayerJSON, err := json.Marshal(penultimateDayPrayer)
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal penultimate day prayer of parsed aladhan prayer calendar")
+		prayersJSON[i], err = json.Marshal(prayers)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal prayers")
+		}
 	}
 
-	lastDayPrayer := parsedPrayerCalendar[len(parsedPrayerCalendar)-1]
-	lastDayPrayerJSON, err := json.Marshal(lastDayPrayer)
+	err = services.Queries.UpsertPrayerCalendar(ctx, repository.UpsertPrayerCalendarParams{
+		LocationKey: location.Key(),
+		Dates:       dates,
+		Latitude:    location.Latitude,
+		Longitude:   location.Longitude,
+		TimeZone:    location.TimeZone,
+		Provider:    providerName,
+		Prayers:     prayersJSON,
+	})
+
 	if err != nil {
-		return errors.Wrap(err, "failed to marshal last day prayer of parsed aladhan prayer calendar")
+		return errors.Wrap(err, "failed to upsert prayer calendar")
 	}
 
-	if isLastDay {
-		prayerCalendar, err = prayer.GetAladhanPrayerCalendar(makeAladhanURL(year, month, timeZone))
-		if err != nil {
-			return errors.Wrap(err, "failed to get aladhan prayer calendar")
-		}
-
-		parsedPrayerCalendar, err = prayer.ParseAladhanPrayerCalendar(prayerCalendar, location)
-		if err != nil {
-			return errors.Wrap(err, "failed to parse aladhan prayer calendar")
-		}
+	return nil
+}
 
-		prayerCalendarJSON, err = json.Marshal(parsedPrayerCalendar)
-		if err != nil {
-			return errors.Wrap(err, "failed to marshal parsed aladhan prayer calendar")
-		}
+// loadPrayerCalendar puts the month in redis, reading it from postgres when
+// it was fetched before and from the providers otherwise.
+func loadPrayerCalendar(ctx context.Context, location prayer.Location, year, month int) error {
+	err := services.PrayerCalendarStore.WarmPrayerCalendar(ctx, location, year, month)
+	if err == nil {
+		return nil
 	}
 
-	err = services.RedisClient.Watch(ctx, func(tx *redis.Tx) error {
-		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-			pipe.Set(ctx, prayer.MakePrayerCalendarKey(timeZone), prayerCalendarJSON, 0)
-			pipe.Set(ctx, prayer.MakeLastDayPrayerKey(timeZone), lastDayPrayerJSON, 0)
-			pipe.Set(ctx, prayer.MakePenultimateDayPrayerKey(timeZone), penultimateDayPrayerJSON, 0)
-			return nil
-		})
-		return err
-	})
+	if errors.Is(err, redis.Nil) == false {
+		return errors.Wrap(err, "failed to warm prayer calendar")
+	}
 
+	prayerCalendar, providerName, err := getPrayerCalendar(ctx, location, year, month)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute redis tx to set prayer calendar and last day prayer")
+		return errors.Wrap(err, "failed to get prayer calendar")
 	}
 
-	newAsynqTask, err := task.NewPrayerRenewalTask(task.PrayerRenewalTask{TimeZone: timeZone, Month: month})
+	err = savePrayerCalendar(ctx, location, providerName, prayerCalendar)
 	if err != nil {
-		return errors.Wrap(err, "failed to create prayer renewal task")
+		return errors.Wrap(err, "failed to save prayer calendar")
 	}
 
-	numOfDays := len(parsedPrayerCalendar)
-	renewalDate := time.Date(year, time.Month(month), numOfDays, 0, 0, 0, 0, now.Location())
-
-	_, err = services.AsynqClient.Enqueue(newAsynqTask, asynq.ProcessIn(renewalDate.Sub(now)))
+	err = services.PrayerCalendarStore.SetPrayerCalendar(ctx, location, prayerCalendar)
 	if err != nil {
-		return errors.Wrap(err, "failed to enqueue prayer renewal task")
+		return errors.Wrap(err, "failed to set prayer calendar")
 	}
 
 	return nil
 }
 
-func InitPrayerReminder(ctx context.Context, location *time.Location) error {
-	timeZone := location.String()
-	prayerCalendar, err := prayer.GetPrayerCalendar(ctx, services.RedisClient, timeZone)
+func ensurePrayerCalendar(ctx context.Context, prayerLocation prayer.Location) error {
+	location, err := time.LoadLocation(prayerLocation.TimeZone)
 	if err != nil {
-		return errors.Wrap(err, "failed to get prayer calendar")
+		return errors.Wrap(err, "failed to load time zone location")
 	}
 
-	lastDayPrayer, err := prayer.GetLastDayPrayer(ctx, services.RedisClient, timeZone)
+	now := services.Clock.Now().In(location)
+	isStored, err := services.PrayerCalendarStore.HasPrayerCalendar(ctx, prayerLocation, now.Year(), int(now.Month()))
 	if err != nil {
-		return errors.Wrap(err, "failed to get last day prayer")
+		return errors.Wrap(err, "failed to check prayer calendar")
 	}
 
-	users, err := services.Queries.GetUsersByTimeZone(
-		ctx,
-		repository.NullIndonesiaTimeZone{
-			IndonesiaTimeZone: repository.IndonesiaTimeZone(timeZone),
-			Valid:             true,
-		},
-	)
+	if isStored {
+		return nil
+	}
 
+	return InitPrayerCalendar(ctx, prayerLocation)
+}
+
+// GetTimeZones returns the time zones of the users along with the ones that
+// have a fallback location.
+func GetTimeZones(ctx context.Context) ([]string, error) {
+	userTimeZones, err := services.Queries.GetTimeZones(ctx)
 	if err != nil {
-		return errors.Wrap(err, "failed to get users by time zone")
+		return nil, errors.Wrap(err, "failed to get time zones")
+	}
+
+	timeZones := prayer.FallbackTimeZones()
+	for _, timeZone := range userTimeZones {
+		if slices.Contains(timeZones, timeZone) == false {
+			timeZones = append(timeZones, timeZone)
+		}
 	}
 
-	now := time.Now().In(location)
-	currentDay := now.Day()
+	return timeZones, nil
+}
 
-	isLastDay := prayer.IsLastDay(now)
-	isPenultimateDay := prayer.IsPenultimateDay(now)
-	isyaPrayer := prayerCalendar[currentDay-1][5]
+// getPrayerLocations returns the fallback location of the time zone and the
+// locations its users set, one per calendar.
+func getPrayerLocations(ctx context.Context, timeZone string) (map[string]prayer.Location, error) {
+	userLocations, err := services.Queries.GetUserLocationsByTimeZone(ctx, pgtype.Text{String: timeZone, Valid: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get user locations by time zone")
+	}
 
-	if isLastDay {
-		isyaPrayer = lastDayPrayer[5]
+	prayerLocations := make(map[string]prayer.Location)
+	defaultLocation, err := prayer.GetTimeZoneLocation(timeZone)
+	if err == nil {
+		prayerLocations[defaultLocation.Key()] = defaultLocation
 	}
 
-	for _, user := range users {
-		now = time.Now().In(location)
-		currentUnixTime := now.Unix()
+	for _, v := range userLocations {
+		prayerLocation := prayer.NewLocation(v.City.String, v.Latitude.Float64, v.Longitude.Float64, timeZone)
+		prayerLocations[prayerLocation.Key()] = prayerLocation
+	}
 
-		var isNextPrayerLastDay bool
-		if (isPenultimateDay && currentUnixTime > isyaPrayer.UnixTime) ||
-			(isLastDay && currentUnixTime < isyaPrayer.UnixTime) {
-			isNextPrayerLastDay = true
-		}
+	return prayerLocations, nil
+}
 
-		var nextPrayer prayer.Prayer
-		if isLastDay && currentUnixTime < isyaPrayer.UnixTime {
-			nextPrayer = prayer.GetNextPrayerByTime(prayerCalendar, lastDayPrayer, currentDay, currentUnixTime)
-		} else {
-			if isLastDay && currentUnixTime > isyaPrayer.UnixTime {
-				currentDay = 1
-			}
+// InitPrayerCalendars stores the calendars of the time zone. A location no
+// provider serves is left to the reconciler, only a failing store stops the
+// others.
+func InitPrayerCalendars(ctx context.Context, location *time.Location) error {
+	prayerLocations, err := getPrayerLocations(ctx, location.String())
+	if err != nil {
+		return err
+	}
 
-			nextPrayer = prayer.GetNextPrayerByTime(prayerCalendar, nil, currentDay, currentUnixTime)
-		}
+	for _, prayerLocation := range prayerLocations {
+		err = InitPrayerCalendar(ctx, prayerLocation)
+		if err != nil && errors.Is(err, errNoPrayerCalendar) {
+			log.Ctx(ctx).
+				Error().
+				Err(err).
+				Caller().
+				Str("location", prayerLocation.Key()).
+				Bool("alert", true).
+				Msg("failed to init prayer calendar, left to the reconciler")
 
-		prayerReminderTaskID := task.MakePrayerReminderTaskID(user.ID, nextPrayer.Name)
-		_, err := services.AsynqInspector.GetTaskInfo(task.DefaultQueue, prayerReminderTaskID)
-		if err != nil && errors.Is(err, asynq.ErrQueueNotFound) {
-			return err
+			continue
 		}
 
-		if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
-			return errors.Wrap(err, "failed to get prayer reminder task info by id")
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to init prayer calendar of %s", prayerLocation.Key()))
 		}
+	}
 
-		if err == nil {
-			continue
-		}
+	return nil
+}
 
-		nextPrayerTime := time.Unix(nextPrayer.UnixTime, 0).In(location)
-		newAsynqTask, err := task.NewPrayerReminderTask(task.PrayerReminderPayload{
-			UserID:         user.ID,
-			PrayerName:     nextPrayer.Name,
-			PrayerUnixTime: nextPrayer.UnixTime,
-			IsLastDay:      isNextPrayerLastDay,
-		})
+// InitPrayerCalendar makes sure the previous, current and next months are
+// stored.
+func InitPrayerCalendar(ctx context.Context, prayerLocation prayer.Location) error {
+	location, err := time.LoadLocation(prayerLocation.TimeZone)
+	if err != nil {
+		return errors.Wrap(err, "failed to load time zone location")
+	}
 
+	now := services.Clock.Now().In(location)
+	firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+	for _, date := range []time.Time{firstDay.AddDate(0, -1, 0), firstDay, firstDay.AddDate(0, 1, 0)} {
+		year, month := date.Year(), int(date.Month())
+		isStored, err := services.PrayerCalendarStore.HasPrayerCalendar(ctx, prayerLocation, year, month)
 		if err != nil {
-			return errors.Wrap(err, "failed to create prayer reminder task")
+			return errors.Wrap(err, "failed to check prayer calendar")
 		}
 
-		_, err = services.AsynqClient.Enqueue(newAsynqTask, asynq.ProcessIn(nextPrayerTime.Sub(now)))
+		if isStored {
+			continue
+		}
+
+		err = loadPrayerCalendar(ctx, prayerLocation, year, month)
 		if err != nil {
-			return errors.Wrap(err, "failed to enqueue prayer reminder task")
+			return errors.Wrap(err, "failed to load prayer calendar")
 		}
 	}
 
 	return nil
 }
 
-func InitPrayerUpdateTask(location *time.Location) error {
-	now := time.Now().In(location)
-	todayAtSix := time.Date(now.Year(), now.Month(), now.Day(), 6, 0, 0, 0, now.Location())
-
-	var targettedTime time.Time
-	if now.Before(todayAtSix) {
-		targettedTime = todayAtSix
-	} else {
-		tomorrow := now.AddDate(0, 0, 1).In(now.Location())
-		tomorrowAtSix := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 6, 0, 0, 0, tomorrow.Location())
-		targettedTime = tomorrowAtSix
+// initPrayerFanout starts the reminder fan-out of the location from the
+// next prayer and reports whether it had to. A running fan-out already
+// queued the first run of the prayer after it, so the check costs one
+// lookup per location.
+func initPrayerFanout(ctx context.Context, prayerLocation prayer.Location) (bool, error) {
+	location, err := time.LoadLocation(prayerLocation.TimeZone)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to load time zone location")
+	}
+
+	now := services.Clock.Now().In(location)
+	nextPrayer, err := services.PrayerCalendarStore.NextPrayerAfter(ctx, prayerLocation, prayer.Adjustment{}, now)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get next prayer")
 	}
 
-	day := targettedTime.Day()
-	prayerUpdateTaskID := task.MakePrayerUpdateTaskID(day)
+	followingPrayer, err := services.PrayerCalendarStore.NextPrayerAfter(
+		ctx,
+		prayerLocation,
+		prayer.Adjustment{},
+		time.Unix(nextPrayer.UnixTime, 0),
+	)
+
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get following prayer")
+	}
 
-	_, err := services.AsynqInspector.GetTaskInfo(task.DefaultQueue, prayerUpdateTaskID)
+	prayerFanoutTaskID := task.MakePrayerFanoutTaskID(prayerLocation.Key(), followingPrayer.Name, followingPrayer.UnixTime, 0)
+	_, err = services.AsynqInspector.GetTaskInfo(task.DefaultQueue, prayerFanoutTaskID)
 	if err != nil && errors.Is(err, asynq.ErrQueueNotFound) {
-		return err
+		return false, err
 	}
 
 	if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
-		return errors.Wrap(err, "failed to get prayer update task info by id")
+		return false, errors.Wrap(err, "failed to get prayer fanout task info by id")
 	}
 
 	if err == nil {
-		return nil
+		return false, nil
 	}
 
-	asynqTask, err := task.NewPrayerUpdateTask(day)
-	if err != nil {
-		return errors.Wrap(err, "failed to create prayer update task")
+	err = enqueuePrayerFanout(task.PrayerFanoutPayload{
+		Location:       prayerLocation,
+		PrayerName:     nextPrayer.Name,
+		PrayerUnixTime: nextPrayer.UnixTime,
+		RunUnixTime:    now.Unix(),
+	}, now)
+
+	if err != nil && errors.Is(err, asynq.ErrTaskIDConflict) {
+		return false, nil
 	}
 
-	_, err = services.AsynqClient.Enqueue(asynqTask, asynq.ProcessIn(targettedTime.Sub(now)))
 	if err != nil {
-		return errors.Wrap(err, "failed to enqueue prayer update task")
+		return false, err
 	}
 
-	return nil
+	return true, nil
 }
 
-func InitTaskRemovalTask(location *time.Location) error {
-	now := time.Now().In(location)
-	tomorrow := now.AddDate(0, 0, 1).In(location)
-	midnight := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 0, 0, 0, 0, tomorrow.Location())
-	day := midnight.Day()
-
-	taskRemovalTaskID := task.MakeTaskRemovalTaskID(day)
-	_, err := services.AsynqInspector.GetTaskInfo(task.DefaultQueue, taskRemovalTaskID)
-	if err != nil && errors.Is(err, asynq.ErrQueueNotFound) {
+// InitPrayerFanouts starts the fan-outs of the time zone. A location without
+// a stored calendar is left to the reconciler, only a failing store or queue
+// stops the others.
+func InitPrayerFanouts(ctx context.Context, location *time.Location) error {
+	prayerLocations, err := getPrayerLocations(ctx, location.String())
+	if err != nil {
 		return err
 	}
 
-	if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
-		return errors.Wrap(err, "failed to get task removal task info by id")
-	}
-
-	if err == nil {
-		return nil
-	}
+	for _, prayerLocation := range prayerLocations {
+		_, err = initPrayerFanout(ctx, prayerLocation)
+		if err != nil && errors.Is(err, redis.Nil) {
+			log.Ctx(ctx).
+				Error().
+				Err(err).
+				Caller().
+				Str("location", prayerLocation.Key()).
+				Bool("alert", true).
+				Msg("failed to init prayer fanout, left to the reconciler")
 
-	asynqTask, err := task.NewTaskRemovalTask(day)
-	if err != nil {
-		return errors.Wrap(err, "failed to create task removal task")
-	}
+			continue
+		}
 
-	_, err = services.AsynqClient.Enqueue(asynqTask, asynq.ProcessIn(midnight.Sub(now)))
-	if err != nil {
-		return errors.Wrap(err, "failed to enqueue task removal task")
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to init prayer fanout of %s", prayerLocation.Key()))
+		}
 	}
 
 	return nil
@@ -2,16 +2,19 @@ package internal
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/hibiken/asynq"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
 func logger(next asynq.Handler) asynq.Handler {
 	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		taskID, _ := asynq.GetTaskID(ctx)
 		subLogger := log.
 			With().
-			Str("task_id", task.ResultWriter().TaskID()).
+			Str("task_id", taskID).
 			Str("task_type", task.Type()).
 			Logger()
 
@@ -23,3 +26,22 @@ func logger(next asynq.Handler) asynq.Handler {
 		return nil
 	})
 }
+
+// classifier keeps asynq from retrying permanent failures, the task is
+// archived right away.
+func classifier(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		err := next.ProcessTask(ctx, task)
+		if err == nil || errors.Is(err, asynq.SkipRetry) {
+			return err
+		}
+
+		errorKind := classifyError(err)
+		if errorKind == permanentErrorKind {
+			log.Ctx(ctx).Warn().Err(err).Str("error_kind", string(errorKind)).Msg("permanent failure, task not retried")
+			return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
+
+		return err
+	})
+}
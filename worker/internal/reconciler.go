@@ -0,0 +1,275 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/pkg/task"
+	"github.com/mdayat/demi-masa/worker/configs/services"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// The reconciler repairs what the fan-outs cannot repair themselves: a
+// location whose fan-out stopped, a run archived after its retries, tasks
+// left behind by deleted users or by locations nobody uses anymore, and
+// periodic runs the scheduler missed.
+const (
+	reconcileInterval = 15 * time.Minute
+	reconcilePageSize = 100
+	// Every reminder of a prayer, the last reminder of isya included, goes
+	// out within this long after the prayer starts.
+	prayerReminderWindow = 12 * time.Hour
+	// A fan-out run archived again after this many requeues keeps failing
+	// the same way, it is left archived for someone to look at.
+	maxFanoutRequeues = 3
+)
+
+func makeFanoutRequeuesKey(taskID string) string {
+	return fmt.Sprintf("fanout:requeues:%s", taskID)
+}
+
+// reconcileResult is reported on the log line of the run, whose alert field
+// is what the alerting watches. The worker exports no metrics.
+type reconcileResult struct {
+	locations        int
+	failedLocations  int
+	restartedFanouts int
+	requeuedFanouts  int
+	// abandonedFanouts are the archived runs left archived, the ones that
+	// failed for good and the ones out of requeues.
+	abandonedFanouts int
+	removedOrphans   int
+	caughtUpTasks    int
+}
+
+// reconcileFanouts makes sure every location in use has its fan-out
+// running, and returns the keys of those locations. A location that fails is
+// counted and tried again on the next run, the others still get reconciled.
+func reconcileFanouts(ctx context.Context, result *reconcileResult) (map[string]bool, error) {
+	timeZones, err := GetTimeZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	locationKeys := make(map[string]bool)
+	for _, timeZone := range timeZones {
+		prayerLocations, err := getPrayerLocations(ctx, timeZone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, prayerLocation := range prayerLocations {
+			locationKeys[prayerLocation.Key()] = true
+			isRestarted, err := reconcileFanout(ctx, prayerLocation)
+			if err != nil {
+				log.Ctx(ctx).
+					Error().
+					Err(err).
+					Caller().
+					Str("location", prayerLocation.Key()).
+					Bool("alert", true).
+					Msg("failed to reconcile prayer fanout")
+
+				result.failedLocations++
+				continue
+			}
+
+			if isRestarted {
+				log.Ctx(ctx).Warn().Str("location", prayerLocation.Key()).Msg("prayer fanout was not running, restarted")
+				result.restartedFanouts++
+			}
+		}
+	}
+
+	result.locations = len(locationKeys)
+	return locationKeys, nil
+}
+
+func reconcileFanout(ctx context.Context, prayerLocation prayer.Location) (bool, error) {
+	err := ensurePrayerCalendar(ctx, prayerLocation)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to ensure prayer calendar")
+	}
+
+	isRestarted, err := initPrayerFanout(ctx, prayerLocation)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to init prayer fanout")
+	}
+
+	return isRestarted, nil
+}
+
+// requeueArchivedFanouts runs the archived fan-out runs of prayers whose
+// reminders are not over yet once more. The reminders they missed by more
+// than fanoutMaxDelay are dropped, and the rest of the prayer's chain is
+// queued again. A run archived by asynq.SkipRetry would fail the same way
+// again, and a run is requeued at most maxFanoutRequeues times.
+func requeueArchivedFanouts(ctx context.Context, locationKeys map[string]bool, now time.Time, result *reconcileResult) error {
+	var taskIDs []string
+	for page := 1; ; page++ {
+		taskInfos, err := services.AsynqInspector.ListArchivedTasks(
+			task.DefaultQueue,
+			asynq.PageSize(reconcilePageSize),
+			asynq.Page(page),
+		)
+
+		if err != nil {
+			return errors.Wrap(err, "failed to list archived tasks")
+		}
+
+		for _, taskInfo := range taskInfos {
+			if taskInfo.Type != task.TypePrayerFanout {
+				continue
+			}
+
+			var payload task.PrayerFanoutPayload
+			if err := json.Unmarshal(taskInfo.Payload, &payload); err != nil {
+				return errors.Wrap(err, "failed to unmarshal prayer fanout task payload")
+			}
+
+			prayerTime := time.Unix(payload.PrayerUnixTime, 0)
+			if locationKeys[payload.Location.Key()] == false || now.Sub(prayerTime) >= prayerReminderWindow {
+				continue
+			}
+
+			// asynq keeps only the message of the error, the classifier
+			// appends the one of asynq.SkipRetry to a permanent failure.
+			if strings.Contains(taskInfo.LastErr, asynq.SkipRetry.Error()) {
+				log.Ctx(ctx).
+					Error().
+					Str("task_id", taskInfo.ID).
+					Str("last_error", taskInfo.LastErr).
+					Bool("alert", true).
+					Msg("archived prayer fanout failed for good, not requeued")
+
+				result.abandonedFanouts++
+				continue
+			}
+			taskIDs = append(taskIDs, taskInfo.ID)
+		}
+
+		if len(taskInfos) < reconcilePageSize {
+			break
+		}
+	}
+
+	for _, taskID := range taskIDs {
+		// The count outlives every reminder of the prayer, so a run that
+		// is still archived then is past the window anyway.
+		key := makeFanoutRequeuesKey(taskID)
+		requeues, err := services.RedisClient.Incr(ctx, key).Result()
+		if err != nil {
+			return errors.Wrap(err, "failed to count prayer fanout requeues")
+		}
+
+		err = services.RedisClient.Expire(ctx, key, prayerReminderWindow).Err()
+		if err != nil {
+			return errors.Wrap(err, "failed to expire prayer fanout requeues")
+		}
+
+		if requeues > maxFanoutRequeues {
+			log.Ctx(ctx).
+				Error().
+				Str("task_id", taskID).
+				Int64("requeues", requeues-1).
+				Bool("alert", true).
+				Msg("archived prayer fanout out of requeues, not requeued")
+
+			result.abandonedFanouts++
+			continue
+		}
+
+		err = services.AsynqInspector.RunTask(task.DefaultQueue, taskID)
+		if err != nil {
+			if errors.Is(err, asynq.ErrTaskNotFound) {
+				continue
+			}
+			return errors.Wrap(err, "failed to run archived prayer fanout task")
+		}
+
+		log.Ctx(ctx).Warn().Str("task_id", taskID).Int64("requeues", requeues).Msg("archived prayer fanout requeued")
+		result.requeuedFanouts++
+	}
+
+	return nil
+}
+
+// removeOrphanTasks deletes the scheduled fan-outs of locations nobody uses
+// anymore and the scheduled downgrades of deleted users. A location picked
+// by its first user during the run may lose its fan-out here, the next run
+// restarts it.
+func removeOrphanTasks(ctx context.Context, locationKeys map[string]bool, result *reconcileResult) error {
+	var orphanTaskIDs []string
+	downgradeTaskIDs := make(map[string]string)
+	for page := 1; ; page++ {
+		taskInfos, err := services.AsynqInspector.ListScheduledTasks(
+			task.DefaultQueue,
+			asynq.PageSize(reconcilePageSize),
+			asynq.Page(page),
+		)
+
+		if err != nil {
+			return errors.Wrap(err, "failed to list scheduled tasks")
+		}
+
+		for _, taskInfo := range taskInfos {
+			switch taskInfo.Type {
+			case task.TypePrayerFanout:
+				var payload task.PrayerFanoutPayload
+				if err := json.Unmarshal(taskInfo.Payload, &payload); err != nil {
+					return errors.Wrap(err, "failed to unmarshal prayer fanout task payload")
+				}
+
+				if locationKeys[payload.Location.Key()] == false {
+					orphanTaskIDs = append(orphanTaskIDs, taskInfo.ID)
+				}
+			case task.TypeUserDowngrade:
+				var payload task.UserDowngradePayload
+				if err := json.Unmarshal(taskInfo.Payload, &payload); err != nil {
+					return errors.Wrap(err, "failed to unmarshal user downgrade task payload")
+				}
+				downgradeTaskIDs[payload.UserID] = taskInfo.ID
+			}
+		}
+
+		if len(taskInfos) < reconcilePageSize {
+			break
+		}
+	}
+
+	if len(downgradeTaskIDs) != 0 {
+		userIDs := make([]string, 0, len(downgradeTaskIDs))
+		for userID := range downgradeTaskIDs {
+			userIDs = append(userIDs, userID)
+		}
+
+		existingUserIDs, err := services.Queries.GetUserIDs(ctx, userIDs)
+		if err != nil {
+			return errors.Wrap(err, "failed to get user ids")
+		}
+
+		for _, userID := range existingUserIDs {
+			delete(downgradeTaskIDs, userID)
+		}
+
+		for _, taskID := range downgradeTaskIDs {
+			orphanTaskIDs = append(orphanTaskIDs, taskID)
+		}
+	}
+
+	for _, taskID := range orphanTaskIDs {
+		err := services.AsynqInspector.DeleteTask(task.DefaultQueue, taskID)
+		if err != nil && errors.Is(err, asynq.ErrTaskNotFound) == false {
+			return errors.Wrap(err, "failed to delete orphan task")
+		}
+		result.removedOrphans++
+	}
+
+	return nil
+}
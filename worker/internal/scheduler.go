@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/mdayat/demi-masa/pkg/task"
+	"github.com/mdayat/demi-masa/worker/configs/env"
+	"github.com/mdayat/demi-masa/worker/configs/services"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+)
+
+// The recurring jobs are declared as cron specs and queued by an asynq
+// periodic task manager, which picks up new time zones and locations on
+// every sync. Every run is keyed by its scheduled time and kept for a period
+// after it completes, so a run missed while the workers were down is queued
+// once by CatchUpPeriodicTasks and a run that happened is not queued twice.
+const (
+	prayerUpdateHour          = 6
+	taskRemovalCronspec       = "0 0 * * *"
+	prayerUpdateCronspec      = "0 %d * * *"
+	prayerRenewalCronspec     = "0 0 1 * *"
+	reminderReconcileCronspec = "*/15 * * * *"
+)
+
+type periodicTask struct {
+	cronspec string
+	// period is the longest gap between two runs, a completed run is kept
+	// for as long.
+	period     time.Duration
+	task       *asynq.Task
+	makeTaskID func(unixTime int64) string
+}
+
+func (p periodicTask) schedule() (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(p.cronspec)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to parse %s cronspec", p.cronspec))
+	}
+
+	return schedule, nil
+}
+
+func (p periodicTask) opts(runTime time.Time) []asynq.Option {
+	return []asynq.Option{asynq.TaskID(p.makeTaskID(runTime.Unix())), asynq.Retention(p.period)}
+}
+
+// getPeriodicTasks returns the maintenance jobs along with the prayer update
+// of every time zone and the prayer renewal of every location in use.
+func getPeriodicTasks(ctx context.Context) ([]periodicTask, error) {
+	taskRemovalTask, err := task.NewTaskRemovalTask()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create task removal task")
+	}
+
+	reminderReconcileTask, err := task.NewReminderReconcileTask()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create reminder reconcile task")
+	}
+
+	periodicTasks := []periodicTask{
+		{
+			cronspec:   fmt.Sprintf("CRON_TZ=%s %s", DefaultTimeZone, taskRemovalCronspec),
+			period:     24 * time.Hour,
+			task:       taskRemovalTask,
+			makeTaskID: task.MakeTaskRemovalTaskID,
+		},
+		{
+			cronspec:   reminderReconcileCronspec,
+			period:     reconcileInterval,
+			task:       reminderReconcileTask,
+			makeTaskID: task.MakeReminderReconcileTaskID,
+		},
+	}
+
+	timeZones, err := GetTimeZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, timeZone := range timeZones {
+		prayerUpdateTask, err := task.NewPrayerUpdateTask(task.PrayerUpdatePayload{TimeZone: timeZone})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create prayer update task")
+		}
+
+		periodicTasks = append(periodicTasks, periodicTask{
+			cronspec: fmt.Sprintf("CRON_TZ=%s "+prayerUpdateCronspec, timeZone, prayerUpdateHour),
+			period:   24 * time.Hour,
+			task:     prayerUpdateTask,
+			makeTaskID: func(unixTime int64) string {
+				return task.MakePrayerUpdateTaskID(timeZone, unixTime)
+			},
+		})
+
+		prayerLocations, err := getPrayerLocations(ctx, timeZone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, prayerLocation := range prayerLocations {
+			prayerRenewalTask, err := task.NewPrayerRenewalTask(task.PrayerRenewalTask{Location: prayerLocation})
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to create prayer renewal task")
+			}
+
+			locationKey := prayerLocation.Key()
+			periodicTasks = append(periodicTasks, periodicTask{
+				cronspec: fmt.Sprintf("CRON_TZ=%s %s", timeZone, prayerRenewalCronspec),
+				period:   31 * 24 * time.Hour,
+				task:     prayerRenewalTask,
+				makeTaskID: func(unixTime int64) string {
+					return task.MakePrayerRenewalTaskID(locationKey, unixTime)
+				},
+			})
+		}
+	}
+
+	return periodicTasks, nil
+}
+
+type periodicTaskConfigProvider struct {
+	ctx context.Context
+}
+
+// GetConfigs keys every job by its next run, the run after it gets its own
+// key on the first sync that follows.
+func (p periodicTaskConfigProvider) GetConfigs() ([]*asynq.PeriodicTaskConfig, error) {
+	periodicTasks, err := getPeriodicTasks(p.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := services.Clock.Now()
+	configs := make([]*asynq.PeriodicTaskConfig, 0, len(periodicTasks))
+	for _, periodicTask := range periodicTasks {
+		schedule, err := periodicTask.schedule()
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, &asynq.PeriodicTaskConfig{
+			Cronspec: periodicTask.cronspec,
+			Task:     periodicTask.task,
+			Opts:     periodicTask.opts(schedule.Next(now)),
+		})
+	}
+
+	return configs, nil
+}
+
+func NewPeriodicTaskConfigProvider(ctx context.Context) asynq.PeriodicTaskConfigProvider {
+	return periodicTaskConfigProvider{ctx: ctx}
+}
+
+func InitPeriodicTaskManager(ctx context.Context) (*asynq.PeriodicTaskManager, error) {
+	periodicTaskManager, err := asynq.NewPeriodicTaskManager(asynq.PeriodicTaskManagerOpts{
+		RedisConnOpt:               asynq.RedisClientOpt{Addr: env.REDIS_URL},
+		PeriodicTaskConfigProvider: NewPeriodicTaskConfigProvider(ctx),
+		SchedulerOpts: &asynq.SchedulerOpts{
+			PostEnqueueFunc: func(taskInfo *asynq.TaskInfo, err error) {
+				if err != nil && errors.Is(err, asynq.ErrTaskIDConflict) == false {
+					log.Ctx(ctx).Error().Err(err).Bool("alert", true).Msg("failed to enqueue periodic task")
+				}
+			},
+		},
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create periodic task manager")
+	}
+
+	return periodicTaskManager, nil
+}
+
+// CatchUpPeriodicTasks queues the last scheduled run of every job under its
+// key, which only goes through when that run never happened.
+func CatchUpPeriodicTasks(ctx context.Context) (int, error) {
+	periodicTasks, err := getPeriodicTasks(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := services.Clock.Now()
+	caughtUp := 0
+	for _, periodicTask := range periodicTasks {
+		schedule, err := periodicTask.schedule()
+		if err != nil {
+			return caughtUp, err
+		}
+
+		var lastRunTime time.Time
+		for runTime := schedule.Next(now.Add(-periodicTask.period)); runTime.After(now) == false; runTime = schedule.Next(runTime) {
+			lastRunTime = runTime
+		}
+
+		if lastRunTime.IsZero() {
+			continue
+		}
+
+		_, err = services.AsynqClient.Enqueue(periodicTask.task, periodicTask.opts(lastRunTime)...)
+		if err != nil && errors.Is(err, asynq.ErrTaskIDConflict) {
+			continue
+		}
+
+		if err != nil {
+			return caughtUp, errors.Wrap(err, fmt.Sprintf("failed to enqueue %s task", periodicTask.task.Type()))
+		}
+
+		log.Ctx(ctx).
+			Warn().
+			Str("task_type", periodicTask.task.Type()).
+			Int64("run_time", lastRunTime.Unix()).
+			Msg("missed periodic task run caught up")
+
+		caughtUp++
+	}
+
+	return caughtUp, nil
+}
@@ -0,0 +1,23 @@
+package simulation
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/mdayat/demi-masa/pkg/notifier"
+)
+
+// Notifier accepts every message, the notifications the worker records
+// tell what went out.
+type Notifier struct {
+	sent atomic.Int64
+}
+
+func (n *Notifier) Notify(ctx context.Context, recipient notifier.Recipient, message notifier.Message) (notifier.Delivery, error) {
+	n.sent.Add(1)
+	return notifier.Delivery{Status: notifier.SentStatus}, nil
+}
+
+func (n *Notifier) Sent() int {
+	return int(n.sent.Load())
+}
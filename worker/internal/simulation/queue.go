@@ -0,0 +1,250 @@
+package simulation
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/hibiken/asynq"
+	"github.com/mdayat/demi-masa/pkg/clock"
+	"github.com/pkg/errors"
+)
+
+type queuedTask struct {
+	info *asynq.TaskInfo
+	task *asynq.Task
+	seq  int
+}
+
+// Queue keeps the tasks the worker enqueues in memory and hands them out in
+// the order they are due on the virtual clock. It behaves like asynq where
+// the worker relies on it: task ids conflict while the task is queued or
+// retained after completing, and the scheduled tasks can be listed, run and
+// deleted.
+type Queue struct {
+	clock     *clock.Virtual
+	mu        sync.Mutex
+	seq       int
+	queued    map[string]*queuedTask
+	completed map[string]time.Time
+}
+
+func NewQueue(clock *clock.Virtual) *Queue {
+	return &Queue{
+		clock:     clock,
+		queued:    make(map[string]*queuedTask),
+		completed: make(map[string]time.Time),
+	}
+}
+
+// taskOptions reads the options the task was created with, which asynq
+// keeps to itself and prepends to the ones given to Enqueue.
+func taskOptions(asynqTask *asynq.Task) []asynq.Option {
+	field := reflect.ValueOf(asynqTask).Elem().FieldByName("opts")
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface().([]asynq.Option)
+}
+
+func (q *Queue) Enqueue(asynqTask *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.clock.Now()
+	q.seq++
+	info := &asynq.TaskInfo{
+		ID:            fmt.Sprintf("simulation:%d", q.seq),
+		Queue:         "default",
+		Type:          asynqTask.Type(),
+		Payload:       asynqTask.Payload(),
+		State:         asynq.TaskStatePending,
+		NextProcessAt: now,
+	}
+
+	for _, opt := range append(taskOptions(asynqTask), opts...) {
+		switch opt.Type() {
+		case asynq.TaskIDOpt:
+			info.ID = opt.Value().(string)
+		case asynq.QueueOpt:
+			info.Queue = opt.Value().(string)
+		case asynq.MaxRetryOpt:
+			info.MaxRetry = opt.Value().(int)
+		case asynq.RetentionOpt:
+			info.Retention = opt.Value().(time.Duration)
+		case asynq.ProcessAtOpt:
+			info.NextProcessAt = opt.Value().(time.Time)
+		case asynq.ProcessInOpt:
+			info.NextProcessAt = now.Add(opt.Value().(time.Duration))
+		}
+	}
+
+	if info.NextProcessAt.After(now) {
+		info.State = asynq.TaskStateScheduled
+	}
+
+	_, isQueued := q.queued[info.ID]
+	expiresAt, isCompleted := q.completed[info.ID]
+	if isQueued || (isCompleted && now.Before(expiresAt)) {
+		return nil, asynq.ErrTaskIDConflict
+	}
+
+	delete(q.completed, info.ID)
+	q.queued[info.ID] = &queuedTask{info: info, task: asynqTask, seq: q.seq}
+	return info, nil
+}
+
+// Next takes the task due first off the queue, tasks due at the same time
+// come out in the order they were enqueued.
+func (q *Queue) Next() (*asynq.TaskInfo, *asynq.Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var next *queuedTask
+	for _, v := range q.queued {
+		if next == nil ||
+			v.info.NextProcessAt.Before(next.info.NextProcessAt) ||
+			(v.info.NextProcessAt.Equal(next.info.NextProcessAt) && v.seq < next.seq) {
+			next = v
+		}
+	}
+
+	if next == nil {
+		return nil, nil, false
+	}
+
+	next.info.State = asynq.TaskStateActive
+	return next.info, next.task, true
+}
+
+// Done removes a processed task, keeping its id for its retention.
+func (q *Queue) Done(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, ok := q.queued[id]
+	if !ok {
+		return
+	}
+
+	delete(q.queued, id)
+	if queued.info.Retention > 0 {
+		q.completed[id] = q.clock.Now().Add(queued.info.Retention)
+	}
+}
+
+// Retry puts a failed task back on the queue to run again at the given time.
+func (q *Queue) Retry(id string, processAt time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, ok := q.queued[id]
+	if !ok {
+		return
+	}
+
+	queued.info.Retried++
+	queued.info.State = asynq.TaskStateRetry
+	queued.info.NextProcessAt = processAt
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+func (q *Queue) GetTaskInfo(queue, id string) (*asynq.TaskInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if queued, ok := q.queued[id]; ok {
+		info := *queued.info
+		return &info, nil
+	}
+
+	if expiresAt, ok := q.completed[id]; ok && q.clock.Now().Before(expiresAt) {
+		return &asynq.TaskInfo{ID: id, Queue: queue, State: asynq.TaskStateCompleted}, nil
+	}
+
+	return nil, asynq.ErrTaskNotFound
+}
+
+// listOptions reads the page size and number of a listing, which asynq
+// keeps unexported.
+func listOptions(opts []asynq.ListOption) (int, int) {
+	pageSize, page := 30, 1
+	for _, opt := range opts {
+		switch fmt.Sprintf("%T", opt) {
+		case "asynq.pageSizeOpt":
+			pageSize = int(reflect.ValueOf(opt).Int())
+		case "asynq.pageNumOpt":
+			page = int(reflect.ValueOf(opt).Int())
+		}
+	}
+	return pageSize, page
+}
+
+func (q *Queue) ListScheduledTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var queuedTasks []*queuedTask
+	for _, v := range q.queued {
+		if v.info.State == asynq.TaskStateScheduled {
+			queuedTasks = append(queuedTasks, v)
+		}
+	}
+
+	sort.Slice(queuedTasks, func(i, j int) bool {
+		return queuedTasks[i].seq < queuedTasks[j].seq
+	})
+
+	pageSize, page := listOptions(opts)
+	start := min((page-1)*pageSize, len(queuedTasks))
+	end := min(start+pageSize, len(queuedTasks))
+
+	taskInfos := make([]*asynq.TaskInfo, 0, end-start)
+	for _, v := range queuedTasks[start:end] {
+		info := *v.info
+		taskInfos = append(taskInfos, &info)
+	}
+
+	return taskInfos, nil
+}
+
+// ListArchivedTasks lists nothing, a simulated task that runs out of
+// retries fails the simulation instead.
+func (q *Queue) ListArchivedTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+	return nil, nil
+}
+
+func (q *Queue) RunTask(queue, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, ok := q.queued[id]
+	if !ok {
+		return asynq.ErrTaskNotFound
+	}
+
+	if queued.info.State == asynq.TaskStateActive {
+		return errors.New(fmt.Sprintf("task %s is active", id))
+	}
+
+	queued.info.State = asynq.TaskStatePending
+	queued.info.NextProcessAt = q.clock.Now()
+	return nil
+}
+
+func (q *Queue) DeleteTask(queue, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.queued[id]; !ok {
+		return asynq.ErrTaskNotFound
+	}
+
+	delete(q.queued, id)
+	return nil
+}
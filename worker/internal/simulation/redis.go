@@ -0,0 +1,176 @@
+package simulation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is an in-memory server for the hash commands the prayer calendar
+// store uses. Clients reach it over in-process connections, so the store
+// runs unchanged on a real go-redis client.
+type Redis struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+}
+
+func NewRedis() *Redis {
+	return &Redis{hashes: make(map[string]map[string]string)}
+}
+
+func (r *Redis) NewClient() *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:             "simulation",
+		Protocol:         2,
+		DisableIndentity: true,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			serverConn, clientConn := net.Pipe()
+			go r.serve(serverConn)
+			return clientConn, nil
+		},
+	})
+}
+
+func (r *Redis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		r.execute(writer, args)
+		if writer.Flush() != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(line, "*") == false {
+		return nil, errors.New(fmt.Sprintf("unexpected command line: %s", line))
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse command length")
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err = readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimPrefix(line, "$"))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse argument length")
+		}
+
+		arg := make([]byte, size+2)
+		_, err = io.ReadFull(reader, arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, string(arg[:size]))
+	}
+
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func writeBulk(writer *bufio.Writer, value string, ok bool) {
+	if !ok {
+		writer.WriteString("$-1\r\n")
+		return
+	}
+	fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(value), value)
+}
+
+func (r *Redis) execute(writer *bufio.Writer, args []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(args) == 0 {
+		writer.WriteString("-ERR empty command\r\n")
+		return
+	}
+
+	command := strings.ToUpper(args[0])
+	switch {
+	case command == "PING":
+		writer.WriteString("+PONG\r\n")
+	case command == "HSET" && len(args) >= 4 && len(args)%2 == 0:
+		hash, ok := r.hashes[args[1]]
+		if !ok {
+			hash = make(map[string]string)
+			r.hashes[args[1]] = hash
+		}
+
+		added := 0
+		for i := 2; i < len(args); i += 2 {
+			if _, ok := hash[args[i]]; !ok {
+				added++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		fmt.Fprintf(writer, ":%d\r\n", added)
+	case command == "HGET" && len(args) == 3:
+		value, ok := r.hashes[args[1]][args[2]]
+		writeBulk(writer, value, ok)
+	case command == "HMGET" && len(args) >= 3:
+		fmt.Fprintf(writer, "*%d\r\n", len(args)-2)
+		for _, field := range args[2:] {
+			value, ok := r.hashes[args[1]][field]
+			writeBulk(writer, value, ok)
+		}
+	case command == "HKEYS" && len(args) == 2:
+		fields := make([]string, 0, len(r.hashes[args[1]]))
+		for field := range r.hashes[args[1]] {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		fmt.Fprintf(writer, "*%d\r\n", len(fields))
+		for _, field := range fields {
+			writeBulk(writer, field, true)
+		}
+	case command == "HDEL" && len(args) >= 3:
+		deleted := 0
+		for _, field := range args[2:] {
+			if _, ok := r.hashes[args[1]][field]; ok {
+				delete(r.hashes[args[1]], field)
+				deleted++
+			}
+		}
+		fmt.Fprintf(writer, ":%d\r\n", deleted)
+	default:
+		fmt.Fprintf(writer, "-ERR unknown command '%s'\r\n", args[0])
+	}
+}
@@ -0,0 +1,301 @@
+package simulation
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mdayat/demi-masa/pkg/clock"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/worker/repository"
+)
+
+// User is a simulated user with the default settings of a new account.
+// A user without coordinates gets the reminders of its time zone's
+// fallback location.
+type User struct {
+	ID          string
+	TimeZone    string
+	City        string
+	Latitude    float64
+	Longitude   float64
+	AccountType repository.AccountType
+}
+
+func (u User) hasCoordinates() bool {
+	return u.Latitude != 0 || u.Longitude != 0
+}
+
+func (u User) Location() (prayer.Location, error) {
+	if u.hasCoordinates() {
+		return prayer.NewLocation(u.City, u.Latitude, u.Longitude, u.TimeZone), nil
+	}
+	return prayer.GetTimeZoneLocation(u.TimeZone)
+}
+
+// DefaultUsers are one user on the fallback location of each indonesian
+// time zone and one on a location of its own, and users abroad on their own
+// locations, in a time zone with daylight saving time and one without.
+var DefaultUsers = []User{
+	{ID: "jakarta-free", TimeZone: "Asia/Jakarta", AccountType: repository.AccountTypeFREE},
+	{ID: "jakarta-premium", TimeZone: "Asia/Jakarta", AccountType: repository.AccountTypePREMIUM},
+	{
+		ID:          "bandung-premium",
+		TimeZone:    "Asia/Jakarta",
+		City:        "Bandung",
+		Latitude:    -6.9175,
+		Longitude:   107.6191,
+		AccountType: repository.AccountTypePREMIUM,
+	},
+	{ID: "makassar-free", TimeZone: "Asia/Makassar", AccountType: repository.AccountTypeFREE},
+	{
+		ID:          "denpasar-premium",
+		TimeZone:    "Asia/Makassar",
+		City:        "Denpasar",
+		Latitude:    -8.6705,
+		Longitude:   115.2126,
+		AccountType: repository.AccountTypePREMIUM,
+	},
+	{ID: "jayapura-premium", TimeZone: "Asia/Jayapura", AccountType: repository.AccountTypePREMIUM},
+	{
+		ID:          "merauke-free",
+		TimeZone:    "Asia/Jayapura",
+		City:        "Merauke",
+		Latitude:    -8.4932,
+		Longitude:   140.4018,
+		AccountType: repository.AccountTypeFREE,
+	},
+	{
+		ID:          "london-premium",
+		TimeZone:    "Europe/London",
+		City:        "London",
+		Latitude:    51.5074,
+		Longitude:   -0.1278,
+		AccountType: repository.AccountTypePREMIUM,
+	},
+	{
+		ID:          "tokyo-free",
+		TimeZone:    "Asia/Tokyo",
+		City:        "Tokyo",
+		Latitude:    35.6762,
+		Longitude:   139.6503,
+		AccountType: repository.AccountTypeFREE,
+	},
+}
+
+type Notification struct {
+	UserID    string
+	TaskID    string
+	Kind      string
+	Status    string
+	CreatedAt time.Time
+}
+
+// Repository implements the worker's queries in memory, the way postgres
+// would answer them for the simulated users.
+type Repository struct {
+	clock           *clock.Virtual
+	mu              sync.Mutex
+	users           []User
+	prayerCalendars map[string]map[string][]byte
+	notifications   []Notification
+}
+
+var _ repository.Querier = (*Repository)(nil)
+
+func NewRepository(clock *clock.Virtual, users []User) *Repository {
+	users = slices.Clone(users)
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	return &Repository{
+		clock:           clock,
+		users:           users,
+		prayerCalendars: make(map[string]map[string][]byte),
+	}
+}
+
+func (r *Repository) Notifications() []Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.notifications)
+}
+
+func (r *Repository) CreateNotification(ctx context.Context, arg repository.CreateNotificationParams) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notifications = append(r.notifications, Notification{
+		UserID:    arg.UserID,
+		TaskID:    arg.TaskID.String,
+		Kind:      arg.Kind,
+		Status:    arg.Status,
+		CreatedAt: r.clock.Now(),
+	})
+
+	return nil
+}
+
+func (r *Repository) DeleteUserDevicesByTokens(ctx context.Context, tokens []string) error {
+	return nil
+}
+
+// GetMessageTemplate finds no template, messages render from the embedded
+// ones.
+func (r *Repository) GetMessageTemplate(ctx context.Context, arg repository.GetMessageTemplateParams) (repository.GetMessageTemplateRow, error) {
+	return repository.GetMessageTemplateRow{}, pgx.ErrNoRows
+}
+
+func (r *Repository) GetPrayerCalendar(ctx context.Context, arg repository.GetPrayerCalendarParams) ([]repository.GetPrayerCalendarRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, to := arg.FromDate.Time.Format(time.DateOnly), arg.ToDate.Time.Format(time.DateOnly)
+	var rows []repository.GetPrayerCalendarRow
+	for date, prayers := range r.prayerCalendars[arg.LocationKey] {
+		if date < from || date > to {
+			continue
+		}
+
+		dateTime, _ := time.Parse(time.DateOnly, date)
+		rows = append(rows, repository.GetPrayerCalendarRow{
+			Date:    pgtype.Date{Time: dateTime, Valid: true},
+			Prayers: prayers,
+		})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Date.Time.Before(rows[j].Date.Time)
+	})
+
+	return rows, nil
+}
+
+func (r *Repository) GetSentNotificationByTaskID(ctx context.Context, arg repository.GetSentNotificationByTaskIDParams) (pgtype.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range r.notifications {
+		if v.TaskID == arg.TaskID.String &&
+			v.UserID == arg.UserID &&
+			v.Kind == arg.Kind &&
+			v.Status != "FAILED" &&
+			v.CreatedAt.After(arg.CreatedAt.Time) {
+			return pgtype.UUID{Valid: true}, nil
+		}
+	}
+
+	return pgtype.UUID{}, pgx.ErrNoRows
+}
+
+func (r *Repository) GetTimeZones(ctx context.Context) ([]string, error) {
+	var timeZones []string
+	for _, user := range r.users {
+		if slices.Contains(timeZones, user.TimeZone) == false {
+			timeZones = append(timeZones, user.TimeZone)
+		}
+	}
+	return timeZones, nil
+}
+
+func (r *Repository) GetUserDeviceTokens(ctx context.Context, userID string) ([]string, error) {
+	return nil, nil
+}
+
+func (r *Repository) GetUserIDs(ctx context.Context, ids []string) ([]string, error) {
+	var userIDs []string
+	for _, user := range r.users {
+		if slices.Contains(ids, user.ID) {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (r *Repository) GetUserLocationsByTimeZone(ctx context.Context, timeZone pgtype.Text) ([]repository.GetUserLocationsByTimeZoneRow, error) {
+	var locationKeys []string
+	var rows []repository.GetUserLocationsByTimeZoneRow
+	for _, user := range r.users {
+		if user.TimeZone != timeZone.String || user.hasCoordinates() == false {
+			continue
+		}
+
+		location, _ := user.Location()
+		if slices.Contains(locationKeys, location.Key()) {
+			continue
+		}
+
+		locationKeys = append(locationKeys, location.Key())
+		rows = append(rows, repository.GetUserLocationsByTimeZoneRow{
+			City:      pgtype.Text{String: user.City, Valid: true},
+			Latitude:  pgtype.Float8{Float64: user.Latitude, Valid: true},
+			Longitude: pgtype.Float8{Float64: user.Longitude, Valid: true},
+		})
+	}
+	return rows, nil
+}
+
+func (r *Repository) GetUserPrayerStreak(ctx context.Context, userID string) (int64, error) {
+	return 0, nil
+}
+
+func (r *Repository) GetUsersByLocationKey(ctx context.Context, arg repository.GetUsersByLocationKeyParams) ([]repository.GetUsersByLocationKeyRow, error) {
+	var rows []repository.GetUsersByLocationKeyRow
+	for _, user := range r.users {
+		location, err := user.Location()
+		if err != nil || location.Key() != arg.LocationKey.String || user.ID <= arg.AfterID {
+			continue
+		}
+
+		if len(rows) == int(arg.PageSize) {
+			break
+		}
+
+		rows = append(rows, repository.GetUsersByLocationKeyRow{
+			ID:                   user.ID,
+			PhoneNumber:          pgtype.Text{String: "+620000000000", Valid: true},
+			Email:                user.ID + "@simulation.local",
+			AccountType:          user.AccountType,
+			AsrMethod:            repository.AsrMethodSHAFII,
+			PrayerOffsets:        []byte("{}"),
+			NotificationChannels: []byte(`["WHATSAPP"]`),
+			QuietWindows:         []byte("[]"),
+			Locale:               "id",
+		})
+	}
+	return rows, nil
+}
+
+func (r *Repository) RemoveCheckedTask(ctx context.Context) error {
+	return nil
+}
+
+func (r *Repository) UpdatePrayersToMissed(ctx context.Context, arg repository.UpdatePrayersToMissedParams) error {
+	return nil
+}
+
+func (r *Repository) UpdateUserSubs(ctx context.Context, arg repository.UpdateUserSubsParams) error {
+	return nil
+}
+
+func (r *Repository) UpsertPrayerCalendar(ctx context.Context, arg repository.UpsertPrayerCalendarParams) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prayerCalendar, ok := r.prayerCalendars[arg.LocationKey]
+	if !ok {
+		prayerCalendar = make(map[string][]byte)
+		r.prayerCalendars[arg.LocationKey] = prayerCalendar
+	}
+
+	for i, date := range arg.Dates {
+		prayerCalendar[date.Time.Format(time.DateOnly)] = arg.Prayers[i]
+	}
+
+	return nil
+}
@@ -0,0 +1,330 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/mdayat/demi-masa/pkg/clock"
+	"github.com/mdayat/demi-masa/pkg/message"
+	"github.com/mdayat/demi-masa/pkg/notifier"
+	"github.com/mdayat/demi-masa/pkg/prayer"
+	"github.com/mdayat/demi-masa/worker/configs/services"
+	"github.com/mdayat/demi-masa/worker/internal"
+	"github.com/mdayat/demi-masa/worker/repository"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// A simulation runs the worker the way main does, on fakes of redis,
+// postgres, the task queue and the notifiers, while a virtual clock jumps
+// from one queued task to the next. The fan-outs, the calendar renewals and
+// the reconciler all run as queued, so months and years go by in seconds.
+// Afterwards every prayer of every user in the window must have been
+// reminded exactly once.
+
+type Config struct {
+	Start time.Time
+	End   time.Time
+	Users []User
+}
+
+type Report struct {
+	Tasks         int
+	Retries       int
+	Notifications int
+	Prayers       int
+	Violations    []string
+}
+
+// prayerReminderKinds are the kinds a prayer reminder goes out as.
+var prayerReminderKinds = []string{
+	string(message.PrayerReminderKind),
+	string(message.PrayerLeadReminderKind),
+	string(message.IftarReminderKind),
+}
+
+func initServices(virtualClock *clock.Virtual, users []User) (*Repository, *Queue, *Notifier, error) {
+	services.Clock = virtualClock
+
+	fakeRepository := NewRepository(virtualClock, users)
+	services.Queries = fakeRepository
+
+	queue := NewQueue(virtualClock)
+	services.AsynqClient = queue
+	services.AsynqInspector = queue
+
+	services.RedisClient = NewRedis().NewClient()
+	services.InitPrayerCalendarStore(services.RedisClient)
+
+	fakeNotifier := &Notifier{}
+	services.Notifier = notifier.NewDispatcher(map[notifier.Channel]notifier.Notifier{
+		notifier.WhatsAppChannel: fakeNotifier,
+	})
+
+	err := services.InitPrayerProviders(prayer.CalculatorProviderName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = services.InitHijriCalendar("")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = services.InitMessageStore()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return fakeRepository, queue, fakeNotifier, nil
+}
+
+// initWorker does what the worker does on startup.
+func initWorker(ctx context.Context) error {
+	timeZones, err := internal.GetTimeZones(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, timeZone := range timeZones {
+		location, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to load %s time zone location", timeZone))
+		}
+
+		err = internal.InitPrayerCalendars(ctx, location)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to init %s prayer calendars", timeZone))
+		}
+
+		err = internal.InitPrayerFanouts(ctx, location)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to init %s prayer fanouts", timeZone))
+		}
+	}
+
+	_, err = internal.CatchUpPeriodicTasks(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to catch up periodic tasks")
+	}
+
+	return nil
+}
+
+// syncPeriodicTasks queues the next run of every periodic task, as the
+// periodic task manager would have registered it by then. A run that is
+// already queued keeps its place.
+func syncPeriodicTasks(provider asynq.PeriodicTaskConfigProvider, queue *Queue, now time.Time) error {
+	configs, err := provider.GetConfigs()
+	if err != nil {
+		return errors.Wrap(err, "failed to get periodic task configs")
+	}
+
+	for _, config := range configs {
+		schedule, err := cron.ParseStandard(config.Cronspec)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to parse %s cronspec", config.Cronspec))
+		}
+
+		opts := append(config.Opts, asynq.ProcessAt(schedule.Next(now)))
+		_, err = queue.Enqueue(config.Task, opts...)
+		if err != nil && errors.Is(err, asynq.ErrTaskIDConflict) == false {
+			return errors.Wrap(err, fmt.Sprintf("failed to enqueue %s task", config.Task.Type()))
+		}
+	}
+
+	return nil
+}
+
+func Run(ctx context.Context, config Config) (Report, error) {
+	var report Report
+	virtualClock := clock.NewVirtual(config.Start)
+	fakeRepository, queue, fakeNotifier, err := initServices(virtualClock, config.Users)
+	if err != nil {
+		return report, err
+	}
+
+	err = initWorker(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	mux := internal.NewServeMux()
+	provider := internal.NewPeriodicTaskConfigProvider(ctx)
+	for {
+		err = syncPeriodicTasks(provider, queue, virtualClock.Now())
+		if err != nil {
+			return report, err
+		}
+
+		taskInfo, asynqTask, ok := queue.Next()
+		if !ok || taskInfo.NextProcessAt.After(config.End) {
+			break
+		}
+
+		virtualClock.Set(taskInfo.NextProcessAt)
+		err = mux.ProcessTask(ctx, asynqTask)
+		report.Tasks++
+
+		if err == nil {
+			queue.Done(taskInfo.ID)
+			continue
+		}
+
+		if errors.Is(err, asynq.SkipRetry) || taskInfo.Retried >= taskInfo.MaxRetry {
+			return report, errors.Wrap(err, fmt.Sprintf("task %s failed for good", taskInfo.ID))
+		}
+
+		report.Retries++
+		delay := internal.RetryDelay(taskInfo.Retried, err, asynqTask)
+		queue.Retry(taskInfo.ID, virtualClock.Now().Add(delay))
+	}
+
+	notifications := fakeRepository.Notifications()
+	report.Notifications = fakeNotifier.Sent()
+	for _, user := range config.Users {
+		err = checkUserReminders(ctx, config, user, notifications, &report)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+type windowedPrayer struct {
+	prayer.Prayer
+	// endUnixTime closes the window of the prayer, the last reminder goes
+	// out within it.
+	endUnixTime int64
+}
+
+// getWindowedPrayers returns the fardhu prayers of the user from the day
+// before start until the day after end.
+func getWindowedPrayers(ctx context.Context, user User, start, end time.Time) ([]windowedPrayer, error) {
+	prayerLocation, err := user.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load time zone location")
+	}
+
+	adjustment, err := prayer.NewAdjustment(string(repository.AsrMethodSHAFII), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var windowedPrayers []windowedPrayer
+	lastDate := end.In(location).AddDate(0, 0, 1)
+	for date := start.In(location).AddDate(0, 0, -1); date.After(lastDate) == false; date = date.AddDate(0, 0, 1) {
+		prayers, err := services.PrayerCalendarStore.GetPrayersForDate(ctx, prayerLocation, adjustment, date)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to get prayers of %s", date.Format(time.DateOnly)))
+		}
+
+		for _, v := range prayers.Fardhu() {
+			windowedPrayer := windowedPrayer{Prayer: v}
+			if v.Name == prayer.SubuhPrayerName {
+				sunrise, _ := prayers.Get(prayer.SunriseTimeName)
+				windowedPrayer.endUnixTime = sunrise.UnixTime
+			}
+
+			if len(windowedPrayers) != 0 && windowedPrayers[len(windowedPrayers)-1].Name != prayer.SubuhPrayerName {
+				windowedPrayers[len(windowedPrayers)-1].endUnixTime = v.UnixTime
+			}
+			windowedPrayers = append(windowedPrayers, windowedPrayer)
+		}
+	}
+
+	return windowedPrayers, nil
+}
+
+// checkUserReminders expects one prayer reminder at every prayer that
+// started in the window, and for premium users one last reminder inside
+// every prayer whose last reminder fell in it. Any other prayer or last
+// reminder is a violation too.
+func checkUserReminders(ctx context.Context, config Config, user User, notifications []Notification, report *Report) error {
+	windowedPrayers, err := getWindowedPrayers(ctx, user, config.Start, config.End)
+	if err != nil {
+		return err
+	}
+
+	var prayerReminders, lastReminders []int64
+	for _, v := range notifications {
+		if v.UserID != user.ID {
+			continue
+		}
+
+		switch {
+		case v.Kind == string(message.LastPrayerReminderKind):
+			lastReminders = append(lastReminders, v.CreatedAt.Unix())
+		case slices.Contains(prayerReminderKinds, v.Kind):
+			prayerReminders = append(prayerReminders, v.CreatedAt.Unix())
+		}
+	}
+
+	expectedPrayerReminders, expectedLastReminders := 0, 0
+	for _, v := range windowedPrayers {
+		if v.UnixTime <= config.Start.Unix() || v.UnixTime > config.End.Unix() {
+			continue
+		}
+
+		report.Prayers++
+		expectedPrayerReminders++
+		prayerTime := time.Unix(v.UnixTime, 0).UTC().Format(time.RFC3339)
+
+		count := countBetween(prayerReminders, v.UnixTime, v.UnixTime)
+		if count != 1 {
+			report.Violations = append(
+				report.Violations,
+				fmt.Sprintf("%s got %d reminders of %s at %s, want 1", user.ID, count, v.Name, prayerTime),
+			)
+		}
+
+		lastReminderUnixTime := prayer.DefaultReminderPreference.LastReminderUnixTime(v.UnixTime, v.endUnixTime)
+		if user.AccountType != repository.AccountTypePREMIUM || lastReminderUnixTime > config.End.Unix() {
+			continue
+		}
+
+		expectedLastReminders++
+		count = countBetween(lastReminders, v.UnixTime+1, v.endUnixTime-1)
+		if count != 1 {
+			report.Violations = append(
+				report.Violations,
+				fmt.Sprintf("%s got %d last reminders of %s at %s, want 1", user.ID, count, v.Name, prayerTime),
+			)
+		}
+	}
+
+	if len(prayerReminders) != expectedPrayerReminders {
+		report.Violations = append(
+			report.Violations,
+			fmt.Sprintf("%s got %d prayer reminders in total, want %d", user.ID, len(prayerReminders), expectedPrayerReminders),
+		)
+	}
+
+	if len(lastReminders) != expectedLastReminders {
+		report.Violations = append(
+			report.Violations,
+			fmt.Sprintf("%s got %d last reminders in total, want %d", user.ID, len(lastReminders), expectedLastReminders),
+		)
+	}
+
+	return nil
+}
+
+func countBetween(unixTimes []int64, from, to int64) int {
+	count := 0
+	for _, v := range unixTimes {
+		if v >= from && v <= to {
+			count++
+		}
+	}
+	return count
+}
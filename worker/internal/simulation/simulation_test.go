@@ -0,0 +1,50 @@
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRun(t *testing.T) {
+	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	tests := []struct {
+		name  string
+		start time.Time
+		days  int
+	}{
+		// The window crosses the end of december and so a year boundary, the
+		// calendar renewal of january and the prayer updates around it.
+		{name: "year boundary", start: time.Date(2026, time.December, 20, 0, 0, 0, 0, time.UTC), days: 20},
+		// The window crosses the end of february into a 31 day month.
+		{name: "month boundary", start: time.Date(2027, time.February, 20, 0, 0, 0, 0, time.UTC), days: 15},
+		// The window crosses the start of british summer time on the 28th
+		// of march and the end of the month.
+		{name: "daylight saving", start: time.Date(2027, time.March, 20, 0, 0, 0, 0, time.UTC), days: 15},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := Run(context.Background(), Config{
+				Start: test.start,
+				End:   test.start.AddDate(0, 0, test.days),
+				Users: DefaultUsers,
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Prayers == 0 {
+				t.Fatal("no prayer was checked")
+			}
+
+			for _, violation := range report.Violations {
+				t.Error(violation)
+			}
+		})
+	}
+}
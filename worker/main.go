@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 	"strconv"
 	"sync"
@@ -9,10 +10,10 @@ import (
 	_ "time/tzdata"
 
 	"github.com/hibiken/asynq"
+	"github.com/mdayat/demi-masa/pkg/notifier"
 	"github.com/mdayat/demi-masa/worker/configs/env"
 	"github.com/mdayat/demi-masa/worker/configs/services"
 	"github.com/mdayat/demi-masa/worker/internal"
-	"github.com/mdayat/demi-masa/worker/repository"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -30,7 +31,7 @@ func main() {
 		logger.Fatal().Err(err).Send()
 	}
 
-	ctx := context.TODO()
+	ctx := logger.WithContext(context.TODO())
 	db, err := services.InitDB(ctx, env.DATABASE_URL)
 	if err != nil {
 		logger.Fatal().Err(err).Send()
@@ -44,107 +45,111 @@ func main() {
 	defer asynqClient.Close()
 	defer asynqInspector.Close()
 
-	services.InitTwilio(env.TWILIO_ACCOUNT_SID, env.TWILIO_AUTH_TOKEN)
-	services.AsynqClient.Enqueue(asynq.NewTask(internal.TypeInitialTask, nil))
-
-	var wg sync.WaitGroup
-	errChan := make(chan error, 3)
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		location, err := time.LoadLocation(string(repository.IndonesiaTimeZoneAsiaJakarta))
+	// Firebase is only needed, and only configured, where push is enabled.
+	if notifier.IsChannelEnabled(env.NOTIFICATION_CHANNELS, notifier.PushChannel) {
+		err = services.InitFirebase(ctx)
 		if err != nil {
-			errChan <- errors.Wrap(err, "failed to load WIB time zone location")
-			return
+			logger.Fatal().Err(err).Send()
 		}
+	}
 
-		err = internal.InitPrayerCalendar(ctx, location)
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to init WIB prayer calendar")
-			return
-		}
+	services.InitTwilio(env.TWILIO_ACCOUNT_SID, env.TWILIO_AUTH_TOKEN)
+	err = services.InitNotifier(notifier.Config{
+		ChannelNames:         env.NOTIFICATION_CHANNELS,
+		TwilioClient:         services.TwilioClient,
+		WhatsAppSender:       env.TWILIO_SENDER,
+		SMSSender:            env.TWILIO_SMS_SENDER,
+		TwilioStatusCallback: env.TWILIO_STATUS_CALLBACK_URL,
+		SMTPHost:             env.SMTP_HOST,
+		SMTPPort:             env.SMTP_PORT,
+		SMTPUsername:         env.SMTP_USERNAME,
+		SMTPPassword:         env.SMTP_PASSWORD,
+		SMTPSender:           env.SMTP_SENDER,
+		PushClient:           services.PushClient,
+		DeviceStore:          services.UserDeviceStore,
+	})
 
-		err = internal.InitPrayerReminder(ctx, location)
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to init WIB prayer reminder")
-			return
-		}
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
 
-		err = internal.InitTaskRemovalTask(location)
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to init task removal task")
-			return
-		}
+	err = services.InitPrayerProviders(env.PRAYER_PROVIDERS)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
 
-		err = internal.InitPrayerUpdateTask(location)
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to init prayer update task")
-			return
-		}
-	}()
+	err = services.InitHijriCalendar(env.HIJRI_OFFSETS)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	err = services.InitMessageStore()
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
 
-		location, err := time.LoadLocation(string(repository.IndonesiaTimeZoneAsiaJayapura))
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to load WIT time zone location")
-			return
-		}
+	services.AsynqClient.Enqueue(asynq.NewTask(internal.TypeInitialTask, nil))
 
-		err = internal.InitPrayerCalendar(ctx, location)
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to init WIT prayer calendar")
-			return
-		}
+	timeZones, err := internal.GetTimeZones(ctx)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
 
-		err = internal.InitPrayerReminder(ctx, location)
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to init WIT prayer reminder")
-			return
-		}
-	}()
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(timeZones))
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	for _, timeZone := range timeZones {
+		wg.Add(1)
+		go func(timeZone string) {
+			defer wg.Done()
 
-		location, err := time.LoadLocation(string(repository.IndonesiaTimeZoneAsiaMakassar))
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to load WITA time zone location")
-			return
-		}
+			location, err := time.LoadLocation(timeZone)
+			if err != nil {
+				errChan <- errors.Wrap(err, fmt.Sprintf("failed to load %s time zone location", timeZone))
+				return
+			}
 
-		err = internal.InitPrayerCalendar(ctx, location)
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to init WITA prayer calendar")
-			return
-		}
+			err = internal.InitPrayerCalendars(ctx, location)
+			if err != nil {
+				errChan <- errors.Wrap(err, fmt.Sprintf("failed to init %s prayer calendars", timeZone))
+				return
+			}
 
-		err = internal.InitPrayerReminder(ctx, location)
-		if err != nil {
-			errChan <- errors.Wrap(err, "failed to init WITA prayer reminder")
-			return
-		}
-	}()
+			err = internal.InitPrayerFanouts(ctx, location)
+			if err != nil {
+				errChan <- errors.Wrap(err, fmt.Sprintf("failed to init %s prayer fanouts", timeZone))
+				return
+			}
+		}(timeZone)
+	}
 
 	go func() {
 		wg.Wait()
 		close(errChan)
 	}()
 
-	for i := 0; i < 3; i++ {
-		select {
-		case err = <-errChan:
-			if err != nil {
-				log.Fatal().Caller().Err(err).Send()
-			}
+	for err = range errChan {
+		if err != nil {
+			log.Fatal().Caller().Err(err).Send()
 		}
 	}
 
+	_, err = internal.CatchUpPeriodicTasks(ctx)
+	if err != nil {
+		logger.Fatal().Err(errors.Wrap(err, "failed to catch up periodic tasks")).Send()
+	}
+
+	periodicTaskManager, err := internal.InitPeriodicTaskManager(ctx)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	err = periodicTaskManager.Start()
+	if err != nil {
+		logger.Fatal().Err(errors.Wrap(err, "failed to start periodic task manager")).Send()
+	}
+	defer periodicTaskManager.Shutdown()
+
 	app, mux := internal.InitApp()
 	if err := app.Run(mux); err != nil {
 		logger.Fatal().Err(err).Send()
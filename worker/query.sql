@@ -1,20 +1,46 @@
--- name: GetUsersByTimeZone :many
+-- name: GetTimeZones :many
+SELECT DISTINCT u.time_zone::VARCHAR AS time_zone FROM "user" u WHERE u.time_zone IS NOT NULL;
+
+-- name: GetUserLocationsByTimeZone :many
+SELECT DISTINCT ON (u.location_key)
+  u.city,
+  u.latitude,
+  u.longitude
+FROM "user" u WHERE u.time_zone = $1 AND u.latitude IS NOT NULL AND u.longitude IS NOT NULL
+ORDER BY u.location_key;
+
+-- name: GetUsersByLocationKey :many
 SELECT
   u.id,
   u.phone_number,
+  u.email,
   u.account_type,
-  u.time_zone
-FROM "user" u WHERE u.time_zone = $1;
+  u.sunnah_reminder,
+  u.asr_method,
+  u.prayer_offsets,
+  u.notification_channels,
+  u.quiet_windows,
+  u.reminders_paused_until,
+  u.locale,
+  rp.enabled,
+  rp.lead_minutes,
+  rp.last_reminder_percent,
+  rp.channel,
+  p.status AS prayer_status
+FROM "user" u
+LEFT JOIN reminder_preference rp ON rp.user_id = u.id AND rp.prayer_name = @prayer_name
+LEFT JOIN prayer p ON p.user_id = u.id AND p.name = @prayer_name AND p.year = @year AND p.month = @month AND p.day = @day
+WHERE u.location_key = @location_key AND u.id > @after_id
+ORDER BY u.id LIMIT @page_size;
 
--- name: GetUserPrayerByID :one
-SELECT
-  u.phone_number,
-  u.account_type,
-  u.time_zone
-FROM "user" u WHERE u.id = $1;
+-- name: GetUserIDs :many
+SELECT u.id FROM "user" u WHERE u.id = ANY(@ids::VARCHAR[]);
+
+-- name: GetUserDeviceTokens :many
+SELECT ud.token FROM user_device ud WHERE ud.user_id = $1;
 
--- name: GetUserPhoneByID :one
-SELECT u.phone_number FROM "user" u WHERE u.id = $1;
+-- name: DeleteUserDevicesByTokens :exec
+DELETE FROM user_device WHERE token = ANY(@tokens::VARCHAR[]);
 
 -- name: UpdateUserSubs :exec
 UPDATE "user" SET account_type = $2 WHERE id = $1;
@@ -23,4 +49,33 @@ UPDATE "user" SET account_type = $2 WHERE id = $1;
 DELETE FROM task WHERE checked = TRUE;
 
 -- name: UpdatePrayersToMissed :exec
-UPDATE prayer SET status = 'MISSED' WHERE status IS NULL AND (day < $1 OR month < $2 OR year < $3);
+UPDATE prayer p SET status = 'MISSED' FROM "user" u
+WHERE p.user_id = u.id AND u.time_zone = $4 AND p.status IS NULL AND (p.day < $1 OR p.month < $2 OR p.year < $3);
+
+-- name: GetPrayerCalendar :many
+SELECT pc.date, pc.prayers FROM prayer_calendar pc
+WHERE pc.location_key = @location_key AND pc.date BETWEEN @from_date AND @to_date
+ORDER BY pc.date;
+
+-- name: UpsertPrayerCalendar :exec
+INSERT INTO prayer_calendar (location_key, date, latitude, longitude, time_zone, provider, prayers)
+SELECT @location_key::VARCHAR, unnest(@dates::DATE[]), @latitude::DOUBLE PRECISION, @longitude::DOUBLE PRECISION, @time_zone::VARCHAR, @provider::VARCHAR, unnest(@prayers::JSONB[])
+ON CONFLICT (location_key, date) DO UPDATE SET provider = EXCLUDED.provider, prayers = EXCLUDED.prayers;
+
+-- name: GetMessageTemplate :one
+SELECT mt.subject, mt.body FROM message_template mt WHERE mt.kind = $1 AND mt.locale = $2;
+
+-- name: GetUserPrayerStreak :one
+SELECT COUNT(DISTINCT make_date(p.year, p.month, p.day)) FROM prayer p
+WHERE p.user_id = $1 AND p.status IN ('ON_TIME', 'LATE') AND make_date(p.year, p.month, p.day) > COALESCE(
+  (SELECT MAX(make_date(m.year, m.month, m.day)) FROM prayer m WHERE m.user_id = $1 AND m.status = 'MISSED'),
+  '-infinity'::DATE
+);
+
+-- name: GetSentNotificationByTaskID :one
+SELECT n.id FROM notification n
+WHERE n.task_id = $1 AND n.user_id = $2 AND n.kind = $3 AND n.status != 'FAILED' AND n.created_at > $4 LIMIT 1;
+
+-- name: CreateNotification :exec
+INSERT INTO notification (user_id, task_id, kind, channel, provider_sid, status, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
@@ -11,68 +11,335 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const getUserPhoneByID = `-- name: GetUserPhoneByID :one
-SELECT u.phone_number FROM "user" u WHERE u.id = $1
+const createNotification = `-- name: CreateNotification :exec
+INSERT INTO notification (user_id, task_id, kind, channel, provider_sid, status, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 `
 
-func (q *Queries) GetUserPhoneByID(ctx context.Context, id string) (pgtype.Text, error) {
-	row := q.db.QueryRow(ctx, getUserPhoneByID, id)
-	var phone_number pgtype.Text
-	err := row.Scan(&phone_number)
-	return phone_number, err
+type CreateNotificationParams struct {
+	UserID      string      `json:"user_id"`
+	TaskID      pgtype.Text `json:"task_id"`
+	Kind        string      `json:"kind"`
+	Channel     pgtype.Text `json:"channel"`
+	ProviderSid pgtype.Text `json:"provider_sid"`
+	Status      string      `json:"status"`
+	Error       pgtype.Text `json:"error"`
 }
 
-const getUserPrayerByID = `-- name: GetUserPrayerByID :one
-SELECT
-  u.phone_number,
-  u.account_type,
-  u.time_zone
-FROM "user" u WHERE u.id = $1
+func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) error {
+	_, err := q.db.Exec(ctx, createNotification,
+		arg.UserID,
+		arg.TaskID,
+		arg.Kind,
+		arg.Channel,
+		arg.ProviderSid,
+		arg.Status,
+		arg.Error,
+	)
+	return err
+}
+
+const deleteUserDevicesByTokens = `-- name: DeleteUserDevicesByTokens :exec
+DELETE FROM user_device WHERE token = ANY($1::VARCHAR[])
+`
+
+func (q *Queries) DeleteUserDevicesByTokens(ctx context.Context, tokens []string) error {
+	_, err := q.db.Exec(ctx, deleteUserDevicesByTokens, tokens)
+	return err
+}
+
+const getMessageTemplate = `-- name: GetMessageTemplate :one
+SELECT mt.subject, mt.body FROM message_template mt WHERE mt.kind = $1 AND mt.locale = $2
 `
 
-type GetUserPrayerByIDRow struct {
-	PhoneNumber pgtype.Text           `json:"phone_number"`
-	AccountType AccountType           `json:"account_type"`
-	TimeZone    NullIndonesiaTimeZone `json:"time_zone"`
+type GetMessageTemplateParams struct {
+	Kind   string `json:"kind"`
+	Locale string `json:"locale"`
+}
+
+type GetMessageTemplateRow struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
 }
 
-func (q *Queries) GetUserPrayerByID(ctx context.Context, id string) (GetUserPrayerByIDRow, error) {
-	row := q.db.QueryRow(ctx, getUserPrayerByID, id)
-	var i GetUserPrayerByIDRow
-	err := row.Scan(&i.PhoneNumber, &i.AccountType, &i.TimeZone)
+func (q *Queries) GetMessageTemplate(ctx context.Context, arg GetMessageTemplateParams) (GetMessageTemplateRow, error) {
+	row := q.db.QueryRow(ctx, getMessageTemplate, arg.Kind, arg.Locale)
+	var i GetMessageTemplateRow
+	err := row.Scan(&i.Subject, &i.Body)
 	return i, err
 }
 
-const getUsersByTimeZone = `-- name: GetUsersByTimeZone :many
+const getPrayerCalendar = `-- name: GetPrayerCalendar :many
+SELECT pc.date, pc.prayers FROM prayer_calendar pc
+WHERE pc.location_key = $1 AND pc.date BETWEEN $2 AND $3
+ORDER BY pc.date
+`
+
+type GetPrayerCalendarParams struct {
+	LocationKey string      `json:"location_key"`
+	FromDate    pgtype.Date `json:"from_date"`
+	ToDate      pgtype.Date `json:"to_date"`
+}
+
+type GetPrayerCalendarRow struct {
+	Date    pgtype.Date `json:"date"`
+	Prayers []byte      `json:"prayers"`
+}
+
+func (q *Queries) GetPrayerCalendar(ctx context.Context, arg GetPrayerCalendarParams) ([]GetPrayerCalendarRow, error) {
+	rows, err := q.db.Query(ctx, getPrayerCalendar, arg.LocationKey, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrayerCalendarRow
+	for rows.Next() {
+		var i GetPrayerCalendarRow
+		if err := rows.Scan(&i.Date, &i.Prayers); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSentNotificationByTaskID = `-- name: GetSentNotificationByTaskID :one
+SELECT n.id FROM notification n
+WHERE n.task_id = $1 AND n.user_id = $2 AND n.kind = $3 AND n.status != 'FAILED' AND n.created_at > $4 LIMIT 1
+`
+
+type GetSentNotificationByTaskIDParams struct {
+	TaskID    pgtype.Text        `json:"task_id"`
+	UserID    string             `json:"user_id"`
+	Kind      string             `json:"kind"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) GetSentNotificationByTaskID(ctx context.Context, arg GetSentNotificationByTaskIDParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, getSentNotificationByTaskID,
+		arg.TaskID,
+		arg.UserID,
+		arg.Kind,
+		arg.CreatedAt,
+	)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getTimeZones = `-- name: GetTimeZones :many
+SELECT DISTINCT u.time_zone::VARCHAR AS time_zone FROM "user" u WHERE u.time_zone IS NOT NULL
+`
+
+func (q *Queries) GetTimeZones(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, getTimeZones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var time_zone string
+		if err := rows.Scan(&time_zone); err != nil {
+			return nil, err
+		}
+		items = append(items, time_zone)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserDeviceTokens = `-- name: GetUserDeviceTokens :many
+SELECT ud.token FROM user_device ud WHERE ud.user_id = $1
+`
+
+func (q *Queries) GetUserDeviceTokens(ctx context.Context, userID string) ([]string, error) {
+	rows, err := q.db.Query(ctx, getUserDeviceTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, err
+		}
+		items = append(items, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserIDs = `-- name: GetUserIDs :many
+SELECT u.id FROM "user" u WHERE u.id = ANY($1::VARCHAR[])
+`
+
+func (q *Queries) GetUserIDs(ctx context.Context, ids []string) ([]string, error) {
+	rows, err := q.db.Query(ctx, getUserIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserLocationsByTimeZone = `-- name: GetUserLocationsByTimeZone :many
+SELECT DISTINCT ON (u.location_key)
+  u.city,
+  u.latitude,
+  u.longitude
+FROM "user" u WHERE u.time_zone = $1 AND u.latitude IS NOT NULL AND u.longitude IS NOT NULL
+ORDER BY u.location_key
+`
+
+type GetUserLocationsByTimeZoneRow struct {
+	City      pgtype.Text   `json:"city"`
+	Latitude  pgtype.Float8 `json:"latitude"`
+	Longitude pgtype.Float8 `json:"longitude"`
+}
+
+func (q *Queries) GetUserLocationsByTimeZone(ctx context.Context, timeZone pgtype.Text) ([]GetUserLocationsByTimeZoneRow, error) {
+	rows, err := q.db.Query(ctx, getUserLocationsByTimeZone, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserLocationsByTimeZoneRow
+	for rows.Next() {
+		var i GetUserLocationsByTimeZoneRow
+		if err := rows.Scan(&i.City, &i.Latitude, &i.Longitude); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserPrayerStreak = `-- name: GetUserPrayerStreak :one
+SELECT COUNT(DISTINCT make_date(p.year, p.month, p.day)) FROM prayer p
+WHERE p.user_id = $1 AND p.status IN ('ON_TIME', 'LATE') AND make_date(p.year, p.month, p.day) > COALESCE(
+  (SELECT MAX(make_date(m.year, m.month, m.day)) FROM prayer m WHERE m.user_id = $1 AND m.status = 'MISSED'),
+  '-infinity'::DATE
+)
+`
+
+func (q *Queries) GetUserPrayerStreak(ctx context.Context, userID string) (int64, error) {
+	row := q.db.QueryRow(ctx, getUserPrayerStreak, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getUsersByLocationKey = `-- name: GetUsersByLocationKey :many
 SELECT
   u.id,
   u.phone_number,
+  u.email,
   u.account_type,
-  u.time_zone
-FROM "user" u WHERE u.time_zone = $1
+  u.sunnah_reminder,
+  u.asr_method,
+  u.prayer_offsets,
+  u.notification_channels,
+  u.quiet_windows,
+  u.reminders_paused_until,
+  u.locale,
+  rp.enabled,
+  rp.lead_minutes,
+  rp.last_reminder_percent,
+  rp.channel,
+  p.status AS prayer_status
+FROM "user" u
+LEFT JOIN reminder_preference rp ON rp.user_id = u.id AND rp.prayer_name = $1
+LEFT JOIN prayer p ON p.user_id = u.id AND p.name = $1 AND p.year = $2 AND p.month = $3 AND p.day = $4
+WHERE u.location_key = $5 AND u.id > $6
+ORDER BY u.id LIMIT $7
 `
 
-type GetUsersByTimeZoneRow struct {
-	ID          string                `json:"id"`
-	PhoneNumber pgtype.Text           `json:"phone_number"`
-	AccountType AccountType           `json:"account_type"`
-	TimeZone    NullIndonesiaTimeZone `json:"time_zone"`
+type GetUsersByLocationKeyParams struct {
+	PrayerName  string      `json:"prayer_name"`
+	Year        int16       `json:"year"`
+	Month       int16       `json:"month"`
+	Day         int16       `json:"day"`
+	LocationKey pgtype.Text `json:"location_key"`
+	AfterID     string      `json:"after_id"`
+	PageSize    int32       `json:"page_size"`
+}
+
+type GetUsersByLocationKeyRow struct {
+	ID                   string             `json:"id"`
+	PhoneNumber          pgtype.Text        `json:"phone_number"`
+	Email                string             `json:"email"`
+	AccountType          AccountType        `json:"account_type"`
+	SunnahReminder       bool               `json:"sunnah_reminder"`
+	AsrMethod            AsrMethod          `json:"asr_method"`
+	PrayerOffsets        []byte             `json:"prayer_offsets"`
+	NotificationChannels []byte             `json:"notification_channels"`
+	QuietWindows         []byte             `json:"quiet_windows"`
+	RemindersPausedUntil pgtype.Timestamptz `json:"reminders_paused_until"`
+	Locale               string             `json:"locale"`
+	Enabled              pgtype.Bool        `json:"enabled"`
+	LeadMinutes          pgtype.Int2        `json:"lead_minutes"`
+	LastReminderPercent  pgtype.Int2        `json:"last_reminder_percent"`
+	Channel              pgtype.Text        `json:"channel"`
+	PrayerStatus         NullPrayerStatus   `json:"prayer_status"`
 }
 
-func (q *Queries) GetUsersByTimeZone(ctx context.Context, timeZone NullIndonesiaTimeZone) ([]GetUsersByTimeZoneRow, error) {
-	rows, err := q.db.Query(ctx, getUsersByTimeZone, timeZone)
+func (q *Queries) GetUsersByLocationKey(ctx context.Context, arg GetUsersByLocationKeyParams) ([]GetUsersByLocationKeyRow, error) {
+	rows, err := q.db.Query(ctx, getUsersByLocationKey,
+		arg.PrayerName,
+		arg.Year,
+		arg.Month,
+		arg.Day,
+		arg.LocationKey,
+		arg.AfterID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetUsersByTimeZoneRow
+	var items []GetUsersByLocationKeyRow
 	for rows.Next() {
-		var i GetUsersByTimeZoneRow
+		var i GetUsersByLocationKeyRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.PhoneNumber,
+			&i.Email,
 			&i.AccountType,
-			&i.TimeZone,
+			&i.SunnahReminder,
+			&i.AsrMethod,
+			&i.PrayerOffsets,
+			&i.NotificationChannels,
+			&i.QuietWindows,
+			&i.RemindersPausedUntil,
+			&i.Locale,
+			&i.Enabled,
+			&i.LeadMinutes,
+			&i.LastReminderPercent,
+			&i.Channel,
+			&i.PrayerStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -94,17 +361,24 @@ func (q *Queries) RemoveCheckedTask(ctx context.Context) error {
 }
 
 const updatePrayersToMissed = `-- name: UpdatePrayersToMissed :exec
-UPDATE prayer SET status = 'MISSED' WHERE status IS NULL AND (day < $1 OR month < $2 OR year < $3)
+UPDATE prayer p SET status = 'MISSED' FROM "user" u
+WHERE p.user_id = u.id AND u.time_zone = $4 AND p.status IS NULL AND (p.day < $1 OR p.month < $2 OR p.year < $3)
 `
 
 type UpdatePrayersToMissedParams struct {
-	Day   int16 `json:"day"`
-	Month int16 `json:"month"`
-	Year  int16 `json:"year"`
+	Day      int16       `json:"day"`
+	Month    int16       `json:"month"`
+	Year     int16       `json:"year"`
+	TimeZone pgtype.Text `json:"time_zone"`
 }
 
 func (q *Queries) UpdatePrayersToMissed(ctx context.Context, arg UpdatePrayersToMissedParams) error {
-	_, err := q.db.Exec(ctx, updatePrayersToMissed, arg.Day, arg.Month, arg.Year)
+	_, err := q.db.Exec(ctx, updatePrayersToMissed,
+		arg.Day,
+		arg.Month,
+		arg.Year,
+		arg.TimeZone,
+	)
 	return err
 }
 
@@ -121,3 +395,32 @@ func (q *Queries) UpdateUserSubs(ctx context.Context, arg UpdateUserSubsParams)
 	_, err := q.db.Exec(ctx, updateUserSubs, arg.ID, arg.AccountType)
 	return err
 }
+
+const upsertPrayerCalendar = `-- name: UpsertPrayerCalendar :exec
+INSERT INTO prayer_calendar (location_key, date, latitude, longitude, time_zone, provider, prayers)
+SELECT $1::VARCHAR, unnest($2::DATE[]), $3::DOUBLE PRECISION, $4::DOUBLE PRECISION, $5::VARCHAR, $6::VARCHAR, unnest($7::JSONB[])
+ON CONFLICT (location_key, date) DO UPDATE SET provider = EXCLUDED.provider, prayers = EXCLUDED.prayers
+`
+
+type UpsertPrayerCalendarParams struct {
+	LocationKey string        `json:"location_key"`
+	Dates       []pgtype.Date `json:"dates"`
+	Latitude    float64       `json:"latitude"`
+	Longitude   float64       `json:"longitude"`
+	TimeZone    string        `json:"time_zone"`
+	Provider    string        `json:"provider"`
+	Prayers     [][]byte      `json:"prayers"`
+}
+
+func (q *Queries) UpsertPrayerCalendar(ctx context.Context, arg UpsertPrayerCalendarParams) error {
+	_, err := q.db.Exec(ctx, upsertPrayerCalendar,
+		arg.LocationKey,
+		arg.Dates,
+		arg.Latitude,
+		arg.Longitude,
+		arg.TimeZone,
+		arg.Provider,
+		arg.Prayers,
+	)
+	return err
+}
@@ -1,7 +1,7 @@
 CREATE TYPE account_type AS ENUM ('FREE', 'PREMIUM');
 CREATE TYPE transaction_status AS ENUM ('UNPAID', 'PAID', 'FAILED', 'EXPIRED', 'REFUND');
-CREATE TYPE indonesia_time_zone AS ENUM ('Asia/Jakarta', 'Asia/Makassar', 'Asia/Jayapura');
 CREATE TYPE prayer_status AS ENUM ('ON_TIME', 'LATE', 'MISSED');
+CREATE TYPE asr_method AS ENUM ('SHAFII', 'HANAFI');
 
 CREATE TABLE "user" (
   id VARCHAR(255),
@@ -10,7 +10,18 @@ CREATE TABLE "user" (
   phone_number VARCHAR(255) UNIQUE,
   phone_verified BOOLEAN DEFAULT FALSE NOT NULL,
   account_type account_type DEFAULT 'FREE' NOT NULL,
-  time_zone indonesia_time_zone,
+  time_zone VARCHAR(255),
+  city VARCHAR(255),
+  latitude DOUBLE PRECISION,
+  longitude DOUBLE PRECISION,
+  sunnah_reminder BOOLEAN DEFAULT FALSE NOT NULL,
+  asr_method asr_method DEFAULT 'SHAFII' NOT NULL,
+  prayer_offsets JSONB DEFAULT '{}' NOT NULL,
+  notification_channels JSONB DEFAULT '["WHATSAPP"]' NOT NULL,
+  quiet_windows JSONB DEFAULT '[]' NOT NULL,
+  reminders_paused_until TIMESTAMPTZ,
+  locale VARCHAR(10) DEFAULT 'id' NOT NULL,
+  location_key VARCHAR(64),
   created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
 
   PRIMARY KEY (id)
@@ -107,3 +118,116 @@ CREATE TABLE task (
     ON UPDATE CASCADE
     ON DELETE CASCADE
 );
+
+CREATE TABLE prayer_calendar (
+  location_key VARCHAR(255),
+  date DATE,
+  latitude DOUBLE PRECISION NOT NULL,
+  longitude DOUBLE PRECISION NOT NULL,
+  time_zone VARCHAR(255) NOT NULL,
+  provider VARCHAR(255) NOT NULL,
+  prayers JSONB NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+  PRIMARY KEY (location_key, date)
+);
+
+CREATE TABLE calendar_feed (
+  user_id VARCHAR(255),
+  token_hash VARCHAR(255) UNIQUE NOT NULL,
+  include_tasks BOOLEAN DEFAULT FALSE NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+  PRIMARY KEY (user_id),
+
+  CONSTRAINT fk_user_calendar_feed
+    FOREIGN KEY (user_id)
+    REFERENCES "user"(id)
+    ON UPDATE CASCADE
+    ON DELETE CASCADE
+);
+
+CREATE TABLE api_key (
+  id UUID DEFAULT gen_random_uuid(),
+  user_id VARCHAR(255) NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  key_hash VARCHAR(255) UNIQUE NOT NULL,
+  daily_quota INT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+  PRIMARY KEY (id),
+
+  CONSTRAINT fk_user_api_key
+    FOREIGN KEY (user_id)
+    REFERENCES "user"(id)
+    ON UPDATE CASCADE
+    ON DELETE CASCADE
+);
+
+CREATE TABLE user_device (
+  id UUID DEFAULT gen_random_uuid(),
+  user_id VARCHAR(255) NOT NULL,
+  token VARCHAR(512) UNIQUE NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+  PRIMARY KEY (id),
+
+  CONSTRAINT fk_user_user_device
+    FOREIGN KEY (user_id)
+    REFERENCES "user"(id)
+    ON UPDATE CASCADE
+    ON DELETE CASCADE
+);
+
+CREATE TABLE reminder_preference (
+  user_id VARCHAR(255),
+  prayer_name VARCHAR(50),
+  enabled BOOLEAN DEFAULT TRUE NOT NULL,
+  lead_minutes SMALLINT DEFAULT 0 NOT NULL,
+  last_reminder_percent SMALLINT DEFAULT 75 NOT NULL,
+  channel VARCHAR(50),
+
+  PRIMARY KEY (user_id, prayer_name),
+
+  CONSTRAINT fk_user_reminder_preference
+    FOREIGN KEY (user_id)
+    REFERENCES "user"(id)
+    ON UPDATE CASCADE
+    ON DELETE CASCADE
+);
+
+CREATE TABLE message_template (
+  kind VARCHAR(100),
+  locale VARCHAR(10),
+  subject VARCHAR(255) NOT NULL,
+  body TEXT NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+  PRIMARY KEY (kind, locale)
+);
+
+CREATE TABLE notification (
+  id UUID DEFAULT gen_random_uuid(),
+  user_id VARCHAR(255) NOT NULL,
+  task_id VARCHAR(255),
+  kind VARCHAR(100) NOT NULL,
+  channel VARCHAR(50),
+  provider_sid VARCHAR(64) UNIQUE,
+  status VARCHAR(50) NOT NULL,
+  error TEXT,
+  created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+  PRIMARY KEY (id),
+
+  CONSTRAINT fk_user_notification
+    FOREIGN KEY (user_id)
+    REFERENCES "user"(id)
+    ON UPDATE CASCADE
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_location_key_id ON "user" (location_key, id);
+CREATE INDEX idx_notification_user_id_created_at ON notification (user_id, created_at);
+CREATE INDEX idx_notification_task_id ON notification (task_id);